@@ -67,6 +67,12 @@ Generate image args
 {{- if .Values.includeLocalNamespaceOnly }}
 {{- $args = append $args (printf "--include-namespaces=%s" .Release.Namespace) }}
 {{- end }}
+{{- if .Values.excludeNamespaces }}
+{{- $args = append $args (printf "--exclude-namespaces=%s" .Values.excludeNamespaces) }}
+{{- end }}
+{{- if .Values.namespaceSelector }}
+{{- $args = append $args (printf "--namespace-selector=%s" .Values.namespaceSelector) }}
+{{- end }}
 {{- if .Values.sealedSecrets.certURL }}
   {{- $args = append $args (printf "--sealed-secrets-cert-url=%s" .Values.sealedSecrets.certURL ) }}
 {{- else }}
@@ -86,6 +92,32 @@ Generate image args
 {{- if .Values.disableLoadSecrets  }}
 {{- $args = append $args "--disable-load-secrets" }}
 {{- end }}
+{{- if .Values.enableCache  }}
+{{- $args = append $args "--enable-cache" }}
+{{- end }}
+{{- if .Values.impersonate  }}
+{{- $args = append $args "--impersonate" }}
+{{- end }}
+{{- if and .Values.authMode (ne .Values.authMode "none") }}
+{{- $args = append $args (printf "--auth-mode=%s" .Values.authMode) }}
+{{- end }}
+{{- with .Values.authHeader }}
+{{- if .userHeader }}
+{{- $args = append $args (printf "--auth-user-header=%s" .userHeader) }}
+{{- end }}
+{{- if .groupsHeader }}
+{{- $args = append $args (printf "--auth-groups-header=%s" .groupsHeader) }}
+{{- end }}
+{{- if .emailHeader }}
+{{- $args = append $args (printf "--auth-email-header=%s" .emailHeader) }}
+{{- end }}
+{{- if .trustedProxies }}
+{{- $args = append $args (printf "--trusted-proxies=%s" .trustedProxies) }}
+{{- end }}
+{{- end }}
+{{- if .Values.accessReview  }}
+{{- $args = append $args "--access-review" }}
+{{- end }}
 {{- if .Values.webLogs  }}
 {{- $args = append $args "--enable-web-logs" }}
 {{- end }}
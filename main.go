@@ -17,17 +17,15 @@ import (
 	auth "github.com/gattma/sealed-secrets-web/pkg/auth/dex"
 	authHandler "github.com/gattma/sealed-secrets-web/pkg/auth/handler"
 	"github.com/gattma/sealed-secrets-web/pkg/auth/middleware"
-	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
 	"github.com/gattma/sealed-secrets-web/pkg/config"
 	"github.com/gattma/sealed-secrets-web/pkg/handler"
 	"github.com/gattma/sealed-secrets-web/pkg/seal"
 	"github.com/gattma/sealed-secrets-web/pkg/version"
 	"github.com/gin-gonic/gin"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 	"k8s.io/client-go/tools/clientcmd"
-
-	"github.com/redis/go-redis/v9"
 )
 
 var (
@@ -35,6 +33,8 @@ var (
 	indexTemplate string
 	//go:embed templates/secret.yaml
 	initialSecretYAML string
+	//go:embed templates/login.html
+	loginTemplate string
 
 	//go:embed static/*
 	static      embed.FS
@@ -59,58 +59,132 @@ func main() {
 		log.Fatalf("Could not read the config: %s", err.Error())
 	}
 
-	authConf, err := authConfig.LoadFromEnv()
-	if err != nil {
-		log.Fatalf("Could not read the auth config: %s", err.Error())
-		return
-	}
-
 	if cfg.PrintVersion {
 		fmt.Println(version.Print("sealed secrets web"))
 		return
 	}
 
-	coreClient, ssc, err := handler.BuildClients(clientConfig, cfg.DisableLoadSecrets)
+	coreClient, ssc, metadataClient, err := handler.BuildClients(clientConfig, cfg.DisableLoadSecrets)
 	if err != nil {
 		log.Fatalf("Could build k8s clients:%v", err.Error())
 	}
-	sealer, err := seal.NewAPISealer(cfg.Ctx, cfg.SealedSecrets)
+	sealers, err := seal.NewAPISealers(cfg.Ctx, cfg.GetControllers())
 	if err != nil {
 		log.Fatalf("Setup sealer: %s", err.Error())
 	}
+	var unsealer seal.Unsealer
+	if cfg.Unseal.Enabled {
+		if unsealer, err = seal.NewKeyUnsealer(cfg.Unseal.KeyFile); err != nil {
+			log.Fatalf("Setup unsealer: %s", err.Error())
+		}
+	}
 
-	// auth
 	ctx := context.Background()
-	authClient, err := auth.New(ctx, authConf.Auth)
+	authn, err := setupAuth(ctx, cfg)
 	if err != nil {
-		log.Fatalf("failed to initialize auth client : %v", err)
+		log.Fatalf("Setup authentication: %s", err.Error())
 	}
-	rdb := redis.NewClient(authConf.RedisClient)
-	sessionStore := store.NewSessionRedisManager(rdb)
-	authMiddleware := middleware.NewAuthMiddleware(
-		ctx,
-		authClient,
-		sessionStore,
-	)
 
 	log.Printf("Running sealed secrets web (%s) on port %d", version.Version, cfg.Web.Port)
-	_ = setupRouter(ctx, coreClient, ssc, cfg, sealer, authMiddleware).Run(fmt.Sprintf(":%d", cfg.Web.Port))
+	_ = setupRouter(ctx, coreClient, ssc, metadataClient, cfg, sealers, unsealer, authn).Run(fmt.Sprintf(":%d", cfg.Web.Port))
+}
+
+// authentication holds the middleware requiring an authenticated user, and the login handler of the oidc mode.
+// Both are nil if the authentication is disabled.
+type authentication struct {
+	requireAuth gin.HandlerFunc
+	login       *authHandler.AuthHandler
+}
+
+// setupAuth creates the authentication of the configured mode.
+func setupAuth(ctx context.Context, cfg *config.Config) (*authentication, error) {
+	switch cfg.Auth.Mode {
+	case config.AuthModeOIDC:
+		aConfig, err := authConfig.LoadFromEnv()
+		if err != nil {
+			return nil, err
+		}
+		authClient, err := auth.New(ctx, aConfig.Auth)
+		if err != nil {
+			return nil, fmt.Errorf("failed to initialize auth client: %w", err)
+		}
+		authStore, sessionStore, err := aConfig.Stores()
+		if err != nil {
+			return nil, err
+		}
+		session := aConfig.Session
+		return &authentication{
+			requireAuth: middleware.NewAuthMiddleware(ctx, authClient, sessionStore, session.MaxLifetime).RequireAuth(),
+			login:       authHandler.NewAuthHandler(authClient, authStore, sessionStore, session.MaxLifetime, session.CookieSecure),
+		}, nil
+	case config.AuthModeHeader:
+		headerMiddleware, err := middleware.NewHeaderMiddleware(cfg.Auth.Header)
+		if err != nil {
+			return nil, err
+		}
+		return &authentication{requireAuth: headerMiddleware.RequireAuth()}, nil
+	default:
+		return &authentication{}, nil
+	}
 }
 
 func setupRouter(
 	ctx context.Context,
 	coreClient corev1.CoreV1Interface,
 	ssClient ssClient.BitnamiV1alpha1Interface,
+	metadataClient metadata.Interface,
 	cfg *config.Config,
-	sealer seal.Sealer,
-	authMiddleware *middleware.AuthMiddleware,
+	sealers map[string]seal.Sealer,
+	unsealer seal.Unsealer,
+	authn *authentication,
 ) *gin.Engine {
 	indexHTML, err := renderIndexHTML(cfg)
 	if err != nil {
 		log.Fatalf("Could not render the index html template: %s", err.Error())
 	}
 
-	sHandler := handler.NewHandler(coreClient, ssClient, cfg)
+	sHandler := handler.NewHandler(coreClient, ssClient, metadataClient, cfg)
+	if cfg.Impersonate {
+		clientsFor, err := handler.BuildImpersonatingClients(clientConfig, cfg.DisableLoadSecrets)
+		if err != nil {
+			log.Fatalf("Could build impersonating k8s clients: %s", err.Error())
+		}
+		sHandler.Impersonate("", clientsFor)
+	}
+	if cfg.AccessReview && !cfg.DisableLoadSecrets {
+		reviews, err := handler.BuildAccessReviewClient(clientConfig)
+		if err != nil {
+			log.Fatalf("Could build the access review client: %s", err.Error())
+		}
+		sHandler.ReviewAccess("", reviews)
+	}
+	for _, ctrl := range cfg.GetControllers() {
+		cc, ssc, mc := coreClient, ssClient, metadataClient
+		if ctrl.KubeContext != "" && !ctrl.Offline() {
+			cc, ssc, mc, err = handler.BuildClients(seal.NewClientConfig(ctrl.KubeContext), cfg.DisableLoadSecrets)
+			if err != nil {
+				log.Fatalf("Could build k8s clients for controller '%s': %s", ctrl.Name, err.Error())
+			}
+		}
+		sHandler.AddController(ctrl.Name, cc, ssc, mc)
+		if cfg.Impersonate && ctrl.KubeContext != "" && !ctrl.Offline() {
+			clientsFor, err := handler.BuildImpersonatingClients(seal.NewClientConfig(ctrl.KubeContext), cfg.DisableLoadSecrets)
+			if err != nil {
+				log.Fatalf("Could build impersonating k8s clients for controller '%s': %s", ctrl.Name, err.Error())
+			}
+			sHandler.Impersonate(ctrl.Name, clientsFor)
+		}
+		if cfg.AccessReview && !cfg.DisableLoadSecrets && ctrl.KubeContext != "" && !ctrl.Offline() {
+			reviews, err := handler.BuildAccessReviewClient(seal.NewClientConfig(ctrl.KubeContext))
+			if err != nil {
+				log.Fatalf("Could build the access review client for controller '%s': %s", ctrl.Name, err.Error())
+			}
+			sHandler.ReviewAccess(ctrl.Name, reviews)
+		}
+	}
+	if cfg.EnableCache {
+		sHandler.StartCache(ctx)
+	}
 
 	r := gin.New()
 	r.Use(gin.Recovery())
@@ -118,58 +192,52 @@ func setupRouter(
 		r.Use(gin.LoggerWithFormatter(ginLogFormatter()))
 	}
 
-	// TODO extract?
-	aConfig, err := authConfig.LoadFromEnv()
-	if err != nil {
-		log.Fatalf("failed to load env file config : %v", err)
-	}
-
-	authClient, err := auth.New(ctx, aConfig.Auth)
-	if err != nil {
-		log.Fatalf("failed to initialize auth client : %v", err)
-	}
-
-	redisClient := redis.NewClient(aConfig.RedisClient)
-	authStore := store.NewAuthRedisManager(redisClient)
-	sessionStore := store.NewSessionRedisManager(redisClient)
-	ah := authHandler.NewAuthHandler(authClient, authStore, sessionStore)
-	// ------------
-	h := handler.New(indexHTML, sealer, cfg)
+	h := handler.New(indexHTML, sealers, unsealer, sHandler, cfg)
 
-	r.GET("/", h.ShowLoginPage) // TODO logout page
 	r.GET("/_health", h.Health)
-
-	protected := r.Group("/")
-	protected.Use(authMiddleware.RequireAuth())
-	{
-		protected.GET("/dashboard", h.Index)
-	}
-
-	// TODO --------
-	// Auth routes will be added later
-	auth := r.Group("/auth")
-	{
-		auth.GET("/login", ah.LoginHandler)
-		auth.GET("/callback", ah.CallbackHandler)
+	r.GET("/_ready", sHandler.Ready)
+
+	switch {
+	case authn.login != nil:
+		r.SetHTMLTemplate(template.Must(template.New("login.html").Parse(loginTemplate)))
+		r.GET("/", h.ShowLoginPage)
+		r.GET("/dashboard", authn.requireAuth, h.Index)
+
+		auth := r.Group("/auth")
+		{
+			auth.GET("/login", authn.login.LoginHandler)
+			auth.GET("/callback", authn.login.CallbackHandler)
+			auth.POST("/logout", authn.login.LogoutHandler)
+			auth.GET("/logged-out", authn.login.LoggedOutHandler)
+		}
+	case authn.requireAuth != nil:
+		r.GET("/", authn.requireAuth, h.Index)
+	default:
+		r.GET("/", h.Index)
 	}
-	// ---------------
 
 	r.StaticFS("/static", http.FS(staticFS))
-	r.LoadHTMLGlob("./templates/*.*")
 
-	// TODO authentication
 	api := r.Group("/api")
-	api.Use(authMiddleware.RequireAuth())
+	if authn.requireAuth != nil {
+		api.Use(authn.requireAuth)
+	}
 	{
 		api.GET("/version", h.Version)
 		api.POST("/raw", h.Raw)
 		api.GET("/certificate", h.Certificate)
+		api.GET("/certificate/info", h.CertificateInfo)
 		api.POST("/kubeseal", h.KubeSeal)
+		api.POST("/merge", h.Merge)
 		api.POST("/dencode", h.Dencode)
 		api.POST("/validate", h.Validate)
+		api.POST("/reencrypt", h.ReEncrypt)
+		api.POST("/unseal", h.Unseal)
 
 		api.GET("/secret/:namespace/:name", sHandler.Secret)
+		api.GET("/sealedsecret/:namespace/:name", sHandler.SealedSecret)
 		api.GET("/secrets", sHandler.AllSecrets)
+		api.GET("/secrets/watch", sHandler.Watch)
 	}
 
 	r.NoRoute(h.RedirectToIndex(cfg.Web.Context))
@@ -183,12 +251,19 @@ func renderIndexHTML(cfg *config.Config) (string, error) {
 		initialSecret = cfg.InitialSecret
 	}
 
+	// the controllers of the selection in the index page, the first one is the default
+	var controllers []string
+	for _, ctrl := range cfg.GetControllers() {
+		controllers = append(controllers, ctrl.Name)
+	}
+
 	data := map[string]interface{}{
-		"DisableLoadSecrets":     cfg.DisableLoadSecrets,
-		"DisableValidateSecrets": cfg.SealedSecrets.CertURL != "",
-		"WebContext":             cfg.Web.Context,
-		"InitialSecret":          initialSecret,
-		"Version":                version.Version,
+		"Controllers":        controllers,
+		"DisableLoadSecrets": cfg.DisableLoadSecrets,
+		"WebContext":         cfg.Web.Context,
+		"InitialSecret":      initialSecret,
+		"Logout":             cfg.Auth.Mode == config.AuthModeOIDC,
+		"Version":            version.Version,
 	}
 
 	var tpl bytes.Buffer
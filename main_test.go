@@ -1,11 +1,14 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"time"
 
 	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	authHandler "github.com/gattma/sealed-secrets-web/pkg/auth/handler"
 	"github.com/gattma/sealed-secrets-web/pkg/config"
 	"github.com/gattma/sealed-secrets-web/pkg/mocks/core"
 	"github.com/gattma/sealed-secrets-web/pkg/mocks/ssclient"
@@ -16,6 +19,7 @@ import (
 	"go.uber.org/mock/gomock"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	metadatafake "k8s.io/client-go/metadata/fake"
 )
 
 var _ = Describe("Main", func() {
@@ -30,6 +34,7 @@ var _ = Describe("Main", func() {
 			ssClient     *ssclient.MockSealedSecretInterface
 			coreClient   *core.MockCoreV1Interface
 			secrets      *core.MockSecretInterface
+			metaClient   *metadatafake.FakeMetadataClient
 			cfg          *config.Config
 		)
 
@@ -45,7 +50,8 @@ var _ = Describe("Main", func() {
 			ssClient = ssclient.NewMockSealedSecretInterface(mock)
 			coreClient = core.NewMockCoreV1Interface(mock)
 			secrets = core.NewMockSecretInterface(mock)
-			router = setupRouter(coreClient, alpha1Client, cfg, nil)
+			metaClient = metadatafake.NewSimpleMetadataClient(metadatafake.NewTestScheme())
+			router = setupRouter(context.Background(), coreClient, alpha1Client, metaClient, cfg, nil, nil, &authentication{})
 		})
 		It("return OK on health", func() {
 			req, _ := http.NewRequest("GET", "/_health", nil)
@@ -89,12 +95,12 @@ var _ = Describe("Main", func() {
 			Ω(w.Code).Should(Equal(http.StatusOK))
 			Ω(
 				w.Body.String(),
-			).Should(Equal(fmt.Sprintf(`{"secrets":[{"namespace":"%s","name":"%s"}]}`, namespace, name)))
+			).Should(Equal(fmt.Sprintf(`{"secrets":[{"namespace":"%s","name":"%s","status":"pending","secretExists":false}],"total":1}`, namespace, name)))
 		})
 
 		It("list sealed secrets only for given namespaces", func() {
 			cfg.IncludeNamespaces = []string{"a", "b"}
-			router = setupRouter(coreClient, alpha1Client, cfg, nil)
+			router = setupRouter(context.Background(), coreClient, alpha1Client, metaClient, cfg, nil, nil, &authentication{})
 			alpha1Client.EXPECT().SealedSecrets("a").Return(ssClient)
 			ssClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(&v1alpha1.SealedSecretList{
 				Items: []v1alpha1.SealedSecret{
@@ -118,7 +124,7 @@ var _ = Describe("Main", func() {
 			Ω(w.Code).Should(Equal(http.StatusOK))
 			Ω(
 				w.Body.String(),
-			).Should(Equal(fmt.Sprintf(`{"secrets":[{"namespace":"%s","name":"%s"},{"namespace":"%s","name":"%s"}]}`, "a", name, "b", name)))
+			).Should(Equal(fmt.Sprintf(`{"secrets":[{"namespace":"%s","name":"%s","status":"pending","secretExists":false},{"namespace":"%s","name":"%s","status":"pending","secretExists":false}],"total":2}`, "a", name, "b", name)))
 		})
 
 		It("get secret from namespace by name", func() {
@@ -142,7 +148,7 @@ var _ = Describe("Main", func() {
 
 		It("secrets endpoints are disabled", func() {
 			cfg.DisableLoadSecrets = true
-			router = setupRouter(coreClient, alpha1Client, cfg, nil)
+			router = setupRouter(context.Background(), coreClient, alpha1Client, metaClient, cfg, nil, nil, &authentication{})
 			req, _ := http.NewRequest("GET", "/api/secrets", nil)
 			router.ServeHTTP(w, req)
 			Ω(w.Code).Should(Equal(403))
@@ -151,4 +157,122 @@ var _ = Describe("Main", func() {
 			Ω(w.Code).Should(Equal(403))
 		})
 	})
+
+	Context("authentication modes", func() {
+		var (
+			w          *httptest.ResponseRecorder
+			mock       *gomock.Controller
+			coreClient *core.MockCoreV1Interface
+			cfg        *config.Config
+		)
+
+		BeforeEach(func() {
+			cfg = &config.Config{
+				FieldFilter: &config.FieldFilter{},
+			}
+			w = httptest.NewRecorder()
+			mock = gomock.NewController(GinkgoT())
+			coreClient = core.NewMockCoreV1Interface(mock)
+		})
+
+		It("keeps the editor and the api open without authentication", func() {
+			cfg.Auth.Mode = config.AuthModeNone
+			authn, err := setupAuth(context.Background(), cfg)
+			Ω(err).ShouldNot(HaveOccurred())
+			router := setupRouter(context.Background(), coreClient, nil, nil, cfg, nil, nil, authn)
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusOK))
+			Ω(w.Body.String()).Should(ContainSubstring("<html"))
+			Ω(w.Body.String()).ShouldNot(ContainSubstring("auth/logout"))
+
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest("GET", "/api/version", nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusOK))
+		})
+
+		It("lists the controllers for selection", func() {
+			cfg.Controllers = []config.Controller{
+				{Name: "cluster"},
+				{Name: "offline", SealedSecrets: config.SealedSecrets{Cert: "cert"}},
+			}
+			indexHTML, err := renderIndexHTML(cfg)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(indexHTML).Should(ContainSubstring(`<option value="cluster">cluster</option>`))
+			Ω(indexHTML).Should(ContainSubstring(`<option value="offline">offline</option>`))
+		})
+
+		It("posts the logout in oidc mode", func() {
+			cfg.Auth.Mode = config.AuthModeOIDC
+			indexHTML, err := renderIndexHTML(cfg)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(indexHTML).Should(ContainSubstring(`<form class="nav-form" method="post" action="auth/logout">`))
+		})
+
+		It("shows the login page and protects the api in oidc mode", func() {
+			authn := &authentication{
+				requireAuth: func(c *gin.Context) {
+					c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+				},
+				login: authHandler.NewAuthHandler(nil, nil, nil, time.Hour, true),
+			}
+			router := setupRouter(context.Background(), coreClient, nil, nil, cfg, nil, nil, authn)
+
+			req, _ := http.NewRequest("GET", "/", nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusOK))
+			Ω(w.Body.String()).Should(ContainSubstring("/auth/login"))
+
+			w = httptest.NewRecorder()
+			req, _ = http.NewRequest("GET", "/auth/logged-out", nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusOK))
+			Ω(w.Body.String()).Should(ContainSubstring("You have been logged out"))
+
+			for _, path := range []string{"/dashboard", "/api/version"} {
+				w = httptest.NewRecorder()
+				req, _ = http.NewRequest("GET", path, nil)
+				router.ServeHTTP(w, req)
+				Ω(w.Code).Should(Equal(http.StatusUnauthorized), path)
+			}
+		})
+
+		It("fails in oidc mode without the provider config", func() {
+			for _, key := range []string{"OIDC_ISSUER_URL", "DEX_URL", "DEX_CLIENT_ID", "DEX_CLIENT_SECRET"} {
+				GinkgoT().Setenv(key, "")
+			}
+			cfg.Auth.Mode = config.AuthModeOIDC
+			_, err := setupAuth(context.Background(), cfg)
+			Ω(err).Should(MatchError(ContainSubstring("missing environment variables")))
+		})
+
+		It("fails in header mode without trusted proxies", func() {
+			cfg.Auth.Mode = config.AuthModeHeader
+			_, err := setupAuth(context.Background(), cfg)
+			Ω(err).Should(MatchError(config.ErrNoTrustedProxies))
+		})
+
+		It("requires the user headers in header mode", func() {
+			cfg.Auth.Mode = config.AuthModeHeader
+			cfg.Auth.Header.TrustedProxies = []string{"10.0.0.0/8"}
+			authn, err := setupAuth(context.Background(), cfg)
+			Ω(err).ShouldNot(HaveOccurred())
+			router := setupRouter(context.Background(), coreClient, nil, nil, cfg, nil, nil, authn)
+
+			for _, path := range []string{"/", "/api/version"} {
+				w = httptest.NewRecorder()
+				req, _ := http.NewRequest("GET", path, nil)
+				req.RemoteAddr = "10.1.2.3:4711"
+				router.ServeHTTP(w, req)
+				Ω(w.Code).Should(Equal(http.StatusUnauthorized), path)
+
+				w = httptest.NewRecorder()
+				req.Header.Set("X-Forwarded-User", "jane")
+				router.ServeHTTP(w, req)
+				Ω(w.Code).Should(Equal(http.StatusOK), path)
+			}
+		})
+	})
 })
@@ -1,70 +1,247 @@
 package config
 
 import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io/fs"
 	"log"
 	"os"
-	"path/filepath"
 	"strconv"
+	"strings"
+	"time"
 
 	auth "github.com/gattma/sealed-secrets-web/pkg/auth/dex"
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
 
 	"github.com/joho/godotenv"
 	"github.com/redis/go-redis/v9"
 )
 
+const (
+	// SessionStoreRedis keeps the sessions in Redis, it can be used with multiple replicas.
+	SessionStoreRedis = "redis"
+	// SessionStoreMemory keeps the sessions in memory, it can only be used with a single replica.
+	SessionStoreMemory = "memory"
+	// SessionStoreCookie keeps the sessions in encrypted cookies.
+	SessionStoreCookie = "cookie"
+)
+
 type Config struct {
-	App         *AppConfig
-	Auth        *auth.Config
+	App     *AppConfig
+	Auth    *auth.Config
+	Session *SessionConfig
+	// RedisClient is only set for the redis session store
 	RedisClient *redis.Options
 }
 type AppConfig struct {
 	Port string
 }
 
+const (
+	// DefaultIdleTimeout is the time after which an unused session expires.
+	DefaultIdleTimeout = store.DefaultSessionTTL
+	// DefaultMaxLifetime is the time after which a session expires, even if it is used.
+	DefaultMaxLifetime = 12 * time.Hour
+)
+
+// SessionConfig selects where the sessions and login states are kept.
+type SessionConfig struct {
+	Store string
+	// CookieKey is the AES key of the cookie store
+	CookieKey []byte
+	// EncryptionKey is the AES key encrypting the refresh tokens
+	EncryptionKey []byte
+	IdleTimeout   time.Duration
+	MaxLifetime   time.Duration
+	// CookieSecure restricts the session cookies to https
+	CookieSecure bool
+}
+
+// LoadFromEnv reads the auth config from the environment. The variables can also be defined in an optional
+// .env file in the current working directory.
 func LoadFromEnv() (*Config, error) {
-	// Get the absolute path of the current working directory
-	currentDir, err := os.Getwd()
-	if err != nil {
-		return nil, err
+	if err := godotenv.Load(".env"); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return nil, fmt.Errorf("error loading .env file: %w", err)
 	}
 
-	// Construct path to .env file in ../cmd/.env
-	envPath := filepath.Join(currentDir, ".", ".env")
-	err = godotenv.Load(envPath)
-
-	if err != nil {
-		log.Fatal("Error loading .env file", err)
+	var missing []string
+	requireEnv := func(key string) string {
+		value := os.Getenv(key)
+		if value == "" {
+			missing = append(missing, key)
+		}
+		return value
 	}
-	redisDB, err := strconv.Atoi(requireEnv("REDIS_DATABASE"))
-	if err != nil {
-		log.Fatal("failed to convert redis db")
+
+	issuerURL, baseURL := os.Getenv("OIDC_ISSUER_URL"), os.Getenv("DEX_URL")
+	if issuerURL == "" && baseURL == "" {
+		missing = append(missing, "OIDC_ISSUER_URL")
 	}
-	return &Config{
+	cfg := &Config{
 		App: &AppConfig{
-			Port: requireEnv("APP_PORT"),
+			Port: os.Getenv("APP_PORT"),
 		},
 		Auth: &auth.Config{
-			BaseURL:      requireEnv("DEX_URL"),
-			ClientID:     requireEnv("DEX_CLIENT_ID"),
-			Realm:        requireEnv("DEX_REALM"),
-			ClientSecret: requireEnv("DEX_CLIENT_SECRET"),
-			RedirectURL:  requireEnv("DEX_REDIRECT_URL"),
+			IssuerURL:          issuerURL,
+			BaseURL:            baseURL,
+			ClientID:           requireEnv("DEX_CLIENT_ID"),
+			Realm:              os.Getenv("DEX_REALM"),
+			ClientSecret:       requireEnv("DEX_CLIENT_SECRET"),
+			RedirectURL:        requireEnv("DEX_REDIRECT_URL"),
+			CAFile:             os.Getenv("OIDC_CA_FILE"),
+			InsecureSkipVerify: os.Getenv("OIDC_INSECURE_SKIP_VERIFY") == "true",
+			Scopes:             strings.Fields(os.Getenv("OIDC_SCOPES")),
+			UsernameClaim:      os.Getenv("OIDC_USERNAME_CLAIM"),
+			GroupsClaim:        os.Getenv("OIDC_GROUPS_CLAIM"),
+
+			PostLogoutRedirectURL: os.Getenv("OIDC_POST_LOGOUT_REDIRECT_URL"),
 		},
-		RedisClient: &redis.Options{
-			Addr: fmt.Sprintf("%s:%s", requireEnv("REDIS_HOST"), requireEnv("REDIS_PORT")),
-			//Username: requireEnv("REDIS_USERNAME"),
-			Password: requireEnv("REDIS_PASSWORD"),
-			//Password: "",
-			DB: redisDB,
+		Session: &SessionConfig{
+			Store: envOrDefault("SESSION_STORE", SessionStoreRedis),
 		},
-	}, nil
+	}
+	var cookieKey string
+	switch cfg.Session.Store {
+	case SessionStoreRedis:
+		cfg.RedisClient = &redis.Options{
+			Addr:     fmt.Sprintf("%s:%s", requireEnv("REDIS_HOST"), envOrDefault("REDIS_PORT", "6379")),
+			Password: os.Getenv("REDIS_PASSWORD"),
+		}
+		// the replicas sharing the sessions have to decrypt the refresh tokens of each other
+		requireEnv("SESSION_ENCRYPTION_KEY")
+	case SessionStoreMemory:
+	case SessionStoreCookie:
+		cookieKey = requireEnv("SESSION_COOKIE_KEY")
+	default:
+		return nil, fmt.Errorf("invalid SESSION_STORE '%s', must be one of redis, memory or cookie", cfg.Session.Store)
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing environment variables: %s", strings.Join(missing, ", "))
+	}
+
+	if cfg.RedisClient != nil {
+		redisDB, err := strconv.Atoi(envOrDefault("REDIS_DATABASE", "0"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid REDIS_DATABASE: %w", err)
+		}
+		cfg.RedisClient.DB = redisDB
+	}
+	var err error
+	if cookieKey != "" {
+		if cfg.Session.CookieKey, err = parseKey("SESSION_COOKIE_KEY", cookieKey); err != nil {
+			return nil, err
+		}
+	}
+	if cfg.Session.EncryptionKey, err = encryptionKey(cfg.Session.CookieKey); err != nil {
+		return nil, err
+	}
+	if cfg.Session.IdleTimeout, err = durationEnv("SESSION_IDLE_TIMEOUT", DefaultIdleTimeout); err != nil {
+		return nil, err
+	}
+	if cfg.Session.MaxLifetime, err = durationEnv("SESSION_MAX_LIFETIME", DefaultMaxLifetime); err != nil {
+		return nil, err
+	}
+	if cfg.Session.CookieSecure, err = boolEnv("SESSION_COOKIE_SECURE", true); err != nil {
+		return nil, err
+	}
+	return cfg, nil
+}
+
+// parseKey decodes the base64 encoded AES key of the variable.
+func parseKey(name, value string) ([]byte, error) {
+	key, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s, it must be base64 encoded: %w", name, err)
+	}
+	if len(key) != 16 && len(key) != 24 && len(key) != 32 {
+		return nil, fmt.Errorf("invalid %s, it must be 16, 24 or 32 bytes long, not %d", name, len(key))
+	}
+	return key, nil
+}
+
+// encryptionKey returns the key encrypting the refresh tokens. Without SESSION_ENCRYPTION_KEY the cookie key is used,
+// or with the memory store a random key that is only valid for this process.
+func encryptionKey(cookieKey []byte) ([]byte, error) {
+	if value := os.Getenv("SESSION_ENCRYPTION_KEY"); value != "" {
+		return parseKey("SESSION_ENCRYPTION_KEY", value)
+	}
+	if cookieKey != nil {
+		return cookieKey, nil
+	}
+	log.Println("SESSION_ENCRYPTION_KEY is not set, sessions can't be refreshed after a restart")
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// durationEnv returns the positive duration of the variable, or the default if it is not set.
+func durationEnv(key string, defaultValue time.Duration) (time.Duration, error) {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue, nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("invalid %s: it must be positive", key)
+	}
+	return d, nil
 }
 
-func requireEnv(key string) string {
+// boolEnv returns the boolean value of the variable, or the default if it is not set.
+func boolEnv(key string, defaultValue bool) (bool, error) {
 	value := os.Getenv(key)
 	if value == "" {
-		panic(fmt.Sprintf("%s is required", key))
+		return defaultValue, nil
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return b, nil
+}
+
+// Stores creates the configured store of the login states and sessions.
+// The refresh tokens of the sessions are encrypted before they are stored.
+func (c *Config) Stores() (store.AuthStore, store.SessionStore, error) {
+	var (
+		authStore    store.AuthStore
+		sessionStore store.SessionStore
+		err          error
+	)
+	switch c.Session.Store {
+	case SessionStoreMemory:
+		authStore, sessionStore = store.NewAuthMemoryManager(), store.NewSessionMemoryManager(c.Session.IdleTimeout)
+	case SessionStoreCookie:
+		if authStore, err = store.NewAuthCookieManager(c.Session.CookieKey, c.Session.CookieSecure); err != nil {
+			return nil, nil, err
+		}
+		if sessionStore, err = store.NewSessionCookieManager(
+			c.Session.CookieKey, c.Session.IdleTimeout, c.Session.CookieSecure,
+		); err != nil {
+			return nil, nil, err
+		}
+	default:
+		redisClient := redis.NewClient(c.RedisClient)
+		authStore = store.NewAuthRedisManager(redisClient)
+		sessionStore = store.NewSessionRedisManager(redisClient, c.Session.IdleTimeout)
+	}
+	encrypted, err := store.NewEncryptedSessionStore(sessionStore, c.Session.EncryptionKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return authStore, encrypted, nil
+}
+
+func envOrDefault(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
 	}
-	return value
+	return defaultValue
 }
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"time"
+
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("LoadFromEnv", func() {
+	BeforeEach(func() {
+		for key, value := range map[string]string{
+			"OIDC_ISSUER_URL":        "https://issuer.example.com",
+			"DEX_URL":                "",
+			"DEX_CLIENT_ID":          "client",
+			"DEX_CLIENT_SECRET":      "secret",
+			"DEX_REDIRECT_URL":       "https://app.example.com/auth/callback",
+			"REDIS_HOST":             "",
+			"SESSION_STORE":          "",
+			"SESSION_COOKIE_KEY":     "",
+			"SESSION_ENCRYPTION_KEY": "",
+			"SESSION_IDLE_TIMEOUT":   "",
+			"SESSION_MAX_LIFETIME":   "",
+			"SESSION_COOKIE_SECURE":  "",
+		} {
+			GinkgoT().Setenv(key, value)
+		}
+	})
+
+	It("requires redis and the encryption key by default", func() {
+		_, err := LoadFromEnv()
+		Ω(err).Should(MatchError("missing environment variables: REDIS_HOST, SESSION_ENCRYPTION_KEY"))
+
+		GinkgoT().Setenv("REDIS_HOST", "redis")
+		GinkgoT().Setenv("SESSION_ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZg==")
+		cfg, err := LoadFromEnv()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.Session.Store).Should(Equal(SessionStoreRedis))
+		Ω(cfg.RedisClient.Addr).Should(Equal("redis:6379"))
+	})
+
+	It("uses the memory store without redis", func() {
+		GinkgoT().Setenv("SESSION_STORE", "memory")
+		cfg, err := LoadFromEnv()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.RedisClient).Should(BeNil())
+		authStore, sessionStore, err := cfg.Stores()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(authStore).Should(BeAssignableToTypeOf(&store.MemoryAuthManager{}))
+		Ω(sessionStore).Should(BeAssignableToTypeOf(&store.EncryptedSessionStore{}))
+		Ω(sessionStore.(*store.EncryptedSessionStore).SessionStore).Should(BeAssignableToTypeOf(&store.MemorySessionManager{}))
+	})
+
+	It("uses the cookie store with the key", func() {
+		GinkgoT().Setenv("SESSION_STORE", "cookie")
+		_, err := LoadFromEnv()
+		Ω(err).Should(MatchError("missing environment variables: SESSION_COOKIE_KEY"))
+
+		GinkgoT().Setenv("SESSION_COOKIE_KEY", "c2hvcnQ=")
+		_, err = LoadFromEnv()
+		Ω(err).Should(MatchError("invalid SESSION_COOKIE_KEY, it must be 16, 24 or 32 bytes long, not 5"))
+
+		GinkgoT().Setenv("SESSION_COOKIE_KEY", "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY=")
+		cfg, err := LoadFromEnv()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.Session.CookieKey).Should(HaveLen(32))
+		authStore, sessionStore, err := cfg.Stores()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(authStore).Should(BeAssignableToTypeOf(&store.CookieAuthManager{}))
+		Ω(sessionStore.(*store.EncryptedSessionStore).SessionStore).Should(BeAssignableToTypeOf(&store.CookieSessionManager{}))
+		Ω(cfg.Session.EncryptionKey).Should(Equal(cfg.Session.CookieKey))
+	})
+
+	It("reads the session lifetimes and the encryption key", func() {
+		GinkgoT().Setenv("SESSION_STORE", "memory")
+		cfg, err := LoadFromEnv()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.Session.IdleTimeout).Should(Equal(DefaultIdleTimeout))
+		Ω(cfg.Session.MaxLifetime).Should(Equal(DefaultMaxLifetime))
+		Ω(cfg.Session.EncryptionKey).Should(HaveLen(32))
+
+		GinkgoT().Setenv("SESSION_IDLE_TIMEOUT", "15m")
+		GinkgoT().Setenv("SESSION_MAX_LIFETIME", "8h")
+		GinkgoT().Setenv("SESSION_ENCRYPTION_KEY", "MDEyMzQ1Njc4OWFiY2RlZg==")
+		cfg, err = LoadFromEnv()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.Session.IdleTimeout).Should(Equal(15 * time.Minute))
+		Ω(cfg.Session.MaxLifetime).Should(Equal(8 * time.Hour))
+		Ω(cfg.Session.EncryptionKey).Should(Equal([]byte("0123456789abcdef")))
+
+		GinkgoT().Setenv("SESSION_MAX_LIFETIME", "-1h")
+		_, err = LoadFromEnv()
+		Ω(err).Should(MatchError("invalid SESSION_MAX_LIFETIME: it must be positive"))
+	})
+
+	It("uses secure cookies unless disabled", func() {
+		GinkgoT().Setenv("SESSION_STORE", "memory")
+		cfg, err := LoadFromEnv()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.Session.CookieSecure).Should(BeTrue())
+
+		GinkgoT().Setenv("SESSION_COOKIE_SECURE", "false")
+		cfg, err = LoadFromEnv()
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(cfg.Session.CookieSecure).Should(BeFalse())
+
+		GinkgoT().Setenv("SESSION_COOKIE_SECURE", "maybe")
+		_, err = LoadFromEnv()
+		Ω(err).Should(MatchError(ContainSubstring("invalid SESSION_COOKIE_SECURE")))
+	})
+
+	It("fails for an unknown store", func() {
+		GinkgoT().Setenv("SESSION_STORE", "etcd")
+		_, err := LoadFromEnv()
+		Ω(err).Should(MatchError(ContainSubstring("invalid SESSION_STORE 'etcd'")))
+	})
+})
@@ -2,53 +2,143 @@ package auth
 
 import (
 	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
 	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"slices"
+	"strings"
 
 	"github.com/coreos/go-oidc/v3/oidc"
 	"golang.org/x/oauth2"
 )
 
+const (
+	// DefaultUsernameClaim is the claim of the ID token holding the username.
+	DefaultUsernameClaim = "preferred_username"
+	// DefaultGroupsClaim is the claim of the ID token holding the groups.
+	DefaultGroupsClaim = "groups"
+)
+
+// DefaultScopes are the scopes requested if no scopes are configured.
+var DefaultScopes = []string{oidc.ScopeOpenID, "profile", "email", "groups"}
+
 type Config struct {
-	BaseURL      string // Authorization base url
+	IssuerURL    string // OIDC issuer url, used for discovery
+	BaseURL      string // Authorization base url, used if no issuer url is set
+	Realm        string // optional realm appended to the base url (/realms/<realm>)
 	ClientID     string // client id oauth
 	RedirectURL  string // valid redirect url
-	ClientSecret string // dex client secret
-	Realm        string // dex realm
+	ClientSecret string // client secret
+	// CAFile is an optional PEM bundle of the CAs trusted for the issuer.
+	CAFile string
+	// InsecureSkipVerify disables the TLS verification of the issuer, only to be used in test setups.
+	InsecureSkipVerify bool
+	Scopes             []string // requested scopes, DefaultScopes if empty
+	UsernameClaim      string   // claim of the username, DefaultUsernameClaim if empty
+	GroupsClaim        string   // claim of the groups, DefaultGroupsClaim if empty
+	// PostLogoutRedirectURL is where the provider redirects to after the logout,
+	// by default the logged-out page next to the redirect url.
+	PostLogoutRedirectURL string
+}
+
+// postLogoutRedirectURL returns the configured url, or the logged-out page next to the redirect url.
+func (c *Config) postLogoutRedirectURL() string {
+	if c.PostLogoutRedirectURL != "" || c.RedirectURL == "" {
+		return c.PostLogoutRedirectURL
+	}
+	redirect, err := url.Parse(c.RedirectURL)
+	if err != nil {
+		return ""
+	}
+	return redirect.ResolveReference(&url.URL{Path: "logged-out"}).String()
+}
+
+// Issuer returns the issuer url. Without issuer url, it is built from the base url and the realm.
+func (c *Config) Issuer() string {
+	if c.IssuerURL != "" {
+		return c.IssuerURL
+	}
+	if c.Realm != "" {
+		return fmt.Sprintf("%s/realms/%s", strings.TrimSuffix(c.BaseURL, "/"), c.Realm)
+	}
+	return c.BaseURL
 }
 
 // Client struct holds all components needed for authentication
 type Client struct {
-	Provider *oidc.Provider        // Handles OIDC protocol operations with dex
-	OIDC     *oidc.IDTokenVerifier // Verifies JWT tokens from dex
-	Oauth    oauth2.Config         // Manages OAuth2 flow (authorization codes, tokens)
+	Provider   *oidc.Provider        // Handles OIDC protocol operations with the issuer
+	OIDC       *oidc.IDTokenVerifier // Verifies JWT tokens from the issuer
+	Oauth      oauth2.Config         // Manages OAuth2 flow (authorization codes, tokens)
+	HTTPClient *http.Client          // Calls the issuer, nil for the default client
+	Claims     Claims                // Names of the user claims
+	// EndSessionEndpoint is the RP-initiated logout endpoint of the provider, empty if it is not supported
+	EndSessionEndpoint    string
+	PostLogoutRedirectURL string
+}
+
+// Claims are the names of the claims holding the user information.
+type Claims struct {
+	Username string
+	Groups   string
 }
 
 func New(ctx context.Context, config *Config) (*Client, error) {
-	// Construct the provider URL using dex realm
-	// providerURL := fmt.Sprintf("%s/realms/%s", config.BaseURL, config.Realm)
-	providerURL := "http://127.0.0.1:5556/dex" // FIXME not hardcode this
+	issuer := config.Issuer()
+	if issuer == "" {
+		return nil, errors.New("the issuer url is required")
+	}
+
+	httpClient, err := newHTTPClient(config)
+	if err != nil {
+		return nil, err
+	}
+	if httpClient != nil {
+		ctx = oidc.ClientContext(ctx, httpClient)
+	}
 
-	provider, err := oidc.NewProvider(ctx, providerURL)
+	provider, err := oidc.NewProvider(ctx, issuer)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get provider: %v", err)
 	}
 
+	var metadata struct {
+		EndSessionEndpoint string `json:"end_session_endpoint"`
+	}
+	if err := provider.Claims(&metadata); err != nil {
+		return nil, fmt.Errorf("failed to read the provider metadata: %w", err)
+	}
+
 	// Create ID token verifier
 	verifier := provider.Verifier(&oidc.Config{
 		ClientID: config.ClientID,
 	})
 
-	// Configure an OpenID Connect aware OAuth2 client with specific scopes:
-	// - oidc.ScopeOpenID: Required for OpenID Connect authentication, provides subject ID (sub)
-	// - "roles": dex-specific scope to get user roles in the token
+	scopes := config.Scopes
+	if len(scopes) == 0 {
+		scopes = DefaultScopes
+	}
+	if !slices.Contains(scopes, oidc.ScopeOpenID) {
+		// required for OpenID Connect authentication, provides subject ID (sub)
+		scopes = append([]string{oidc.ScopeOpenID}, scopes...)
+	}
 	oauth2Config := oauth2.Config{
 		ClientID:     config.ClientID,
 		ClientSecret: config.ClientSecret,
 		RedirectURL:  config.RedirectURL,
 		Endpoint:     provider.Endpoint(),
-		Scopes: []string{
-			oidc.ScopeOpenID, // Required for OIDC authentication
-		},
+		Scopes:       scopes,
+	}
+
+	claims := Claims{Username: config.UsernameClaim, Groups: config.GroupsClaim}
+	if claims.Username == "" {
+		claims.Username = DefaultUsernameClaim
+	}
+	if claims.Groups == "" {
+		claims.Groups = DefaultGroupsClaim
 	}
 
 	// Return initialized client with all required components
@@ -65,14 +155,103 @@ func New(ctx context.Context, config *Config) (*Client, error) {
 		// - Extracts user information
 		OIDC: verifier,
 
-		// provider: dex OIDC provider that:
+		// provider: OIDC provider that:
 		// - Provides endpoint URLs (auth, token)
 		// - Handles OIDC protocol details
 		// - Manages provider metadata
 		Provider: provider,
+
+		HTTPClient: httpClient,
+		Claims:     claims,
+
+		EndSessionEndpoint:    metadata.EndSessionEndpoint,
+		PostLogoutRedirectURL: config.postLogoutRedirectURL(),
 	}, nil
 }
 
+// newHTTPClient returns a client trusting the configured CAs, or nil if the default client can be used.
+func newHTTPClient(config *Config) (*http.Client, error) {
+	if config.CAFile == "" && !config.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion:         tls.VersionTLS12,
+		InsecureSkipVerify: config.InsecureSkipVerify, //nolint:gosec // only enabled for test setups
+	}
+	if config.CAFile != "" {
+		pem, err := os.ReadFile(config.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read the CA file: %w", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in the CA file %s", config.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.TLSClientConfig = tlsConfig
+	return &http.Client{Transport: transport}, nil
+}
+
+// Context returns a context making the OAuth2 calls with the client trusting the configured CAs.
+func (c *Client) Context(ctx context.Context) context.Context {
+	if c.HTTPClient == nil {
+		return ctx
+	}
+	return oidc.ClientContext(ctx, c.HTTPClient)
+}
+
+// EndSessionURL returns the url of the RP-initiated logout at the provider, or an empty string if the provider
+// doesn't support it. The id token hint is optional.
+func (c *Client) EndSessionURL(idToken string) string {
+	if c.EndSessionEndpoint == "" {
+		return ""
+	}
+	endSession, err := url.Parse(c.EndSessionEndpoint)
+	if err != nil {
+		return ""
+	}
+	query := endSession.Query()
+	if idToken != "" {
+		query.Set("id_token_hint", idToken)
+	}
+	if c.PostLogoutRedirectURL != "" {
+		query.Set("post_logout_redirect_uri", c.PostLogoutRedirectURL)
+	}
+	query.Set("client_id", c.Oauth.ClientID)
+	endSession.RawQuery = query.Encode()
+	return endSession.String()
+}
+
+// Refresh returns new tokens for the refresh token.
+func (c *Client) Refresh(ctx context.Context, refreshToken string) (*oauth2.Token, error) {
+	// a token without access token is expired, so the token source refreshes it
+	return c.Oauth.TokenSource(c.Context(ctx), &oauth2.Token{RefreshToken: refreshToken}).Token()
+}
+
+// UserInfo extracts the username and groups from the claims. Without username claim, the subject is used.
+func (c *Client) UserInfo(claims map[string]interface{}) (username string, groups []string) {
+	username, _ = claims[c.Claims.Username].(string)
+	if username == "" {
+		username, _ = claims["sub"].(string)
+	}
+	switch g := claims[c.Claims.Groups].(type) {
+	case string:
+		groups = []string{g}
+	case []interface{}:
+		for _, v := range g {
+			if s, ok := v.(string); ok {
+				groups = append(groups, s)
+			}
+		}
+	}
+	return username, groups
+}
+
 // AuthCodeURL generates the login URL for OAuth2 authorization code flow.
 // It returns a URL that the user should be redirected to for authentication.
 // The state parameter is a random string that will be validated in the callback
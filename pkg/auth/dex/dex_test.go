@@ -0,0 +1,137 @@
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"golang.org/x/oauth2"
+)
+
+var _ = Describe("Auth", func() {
+	Context("Issuer", func() {
+		It("should use the issuer url", func() {
+			c := &Config{IssuerURL: "https://issuer.example.com", BaseURL: "https://base.example.com", Realm: "r"}
+			Ω(c.Issuer()).Should(Equal("https://issuer.example.com"))
+		})
+		It("should append the realm to the base url", func() {
+			c := &Config{BaseURL: "https://base.example.com/", Realm: "r"}
+			Ω(c.Issuer()).Should(Equal("https://base.example.com/realms/r"))
+		})
+		It("should use the base url without realm", func() {
+			c := &Config{BaseURL: "https://base.example.com/dex"}
+			Ω(c.Issuer()).Should(Equal("https://base.example.com/dex"))
+		})
+	})
+
+	Context("New", func() {
+		var server *httptest.Server
+		BeforeEach(func() {
+			server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(map[string]string{
+					"issuer":                 server.URL,
+					"authorization_endpoint": server.URL + "/auth",
+					"token_endpoint":         server.URL + "/token",
+					"jwks_uri":               server.URL + "/keys",
+				})
+			}))
+			DeferCleanup(server.Close)
+		})
+
+		It("should fail without issuer", func() {
+			_, err := New(context.Background(), &Config{})
+			Ω(err).Should(MatchError("the issuer url is required"))
+		})
+
+		It("should fail if the issuer certificate is not trusted", func() {
+			_, err := New(context.Background(), &Config{IssuerURL: server.URL})
+			Ω(err).Should(MatchError(ContainSubstring("failed to get provider")))
+		})
+
+		It("should trust the CA file", func() {
+			caFile := filepath.Join(GinkgoT().TempDir(), "ca.pem")
+			caPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+			Ω(os.WriteFile(caFile, caPEM, 0o600)).Should(Succeed())
+
+			client, err := New(context.Background(), &Config{IssuerURL: server.URL, CAFile: caFile, ClientID: "ssw"})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(client.Oauth.Endpoint.AuthURL).Should(Equal(server.URL + "/auth"))
+			Ω(client.Oauth.Scopes).Should(Equal(DefaultScopes))
+			Ω(client.Claims).Should(Equal(Claims{Username: DefaultUsernameClaim, Groups: DefaultGroupsClaim}))
+		})
+
+		It("should skip the TLS verification", func() {
+			client, err := New(context.Background(), &Config{
+				IssuerURL:          server.URL,
+				InsecureSkipVerify: true,
+				Scopes:             []string{"email", "roles"},
+				UsernameClaim:      "email",
+				GroupsClaim:        "roles",
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(client.Oauth.Scopes).Should(Equal([]string{"openid", "email", "roles"}))
+			Ω(client.Claims).Should(Equal(Claims{Username: "email", Groups: "roles"}))
+			Ω(client.EndSessionEndpoint).Should(BeEmpty())
+		})
+	})
+
+	Context("UserInfo", func() {
+		client := &Client{Claims: Claims{Username: "preferred_username", Groups: "groups"}}
+
+		It("should read the configured claims", func() {
+			username, groups := client.UserInfo(map[string]interface{}{
+				"sub":                "1234",
+				"preferred_username": "alice",
+				"groups":             []interface{}{"team-a", "team-b"},
+			})
+			Ω(username).Should(Equal("alice"))
+			Ω(groups).Should(Equal([]string{"team-a", "team-b"}))
+		})
+
+		It("should fall back to the subject and accept a single group", func() {
+			username, groups := client.UserInfo(map[string]interface{}{"sub": "1234", "groups": "team-a"})
+			Ω(username).Should(Equal("1234"))
+			Ω(groups).Should(Equal([]string{"team-a"}))
+		})
+	})
+
+	Context("EndSessionURL", func() {
+		It("should not log out at the provider without end session endpoint", func() {
+			client := &Client{}
+			Ω(client.EndSessionURL("token")).Should(BeEmpty())
+		})
+
+		It("should add the id token hint and the post logout redirect url", func() {
+			client := &Client{
+				Oauth:                 oauth2.Config{ClientID: "ssw"},
+				EndSessionEndpoint:    "https://issuer.example.com/logout?realm=r",
+				PostLogoutRedirectURL: "https://ssw.example.com/auth/logged-out",
+			}
+			endSession, err := url.Parse(client.EndSessionURL("token"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(endSession.Host).Should(Equal("issuer.example.com"))
+			Ω(endSession.Path).Should(Equal("/logout"))
+			Ω(endSession.Query()).Should(Equal(url.Values{
+				"realm":                    {"r"},
+				"id_token_hint":            {"token"},
+				"post_logout_redirect_uri": {"https://ssw.example.com/auth/logged-out"},
+				"client_id":                {"ssw"},
+			}))
+		})
+
+		It("should derive the post logout redirect url from the redirect url", func() {
+			c := &Config{RedirectURL: "https://ssw.example.com/ctx/auth/callback"}
+			Ω(c.postLogoutRedirectURL()).Should(Equal("https://ssw.example.com/ctx/auth/logged-out"))
+			c.PostLogoutRedirectURL = "https://ssw.example.com/"
+			Ω(c.postLogoutRedirectURL()).Should(Equal("https://ssw.example.com/"))
+		})
+	})
+})
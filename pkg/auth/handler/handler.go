@@ -12,6 +12,7 @@ import (
 	"github.com/gattma/sealed-secrets-web/pkg/auth/dex"
 	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
 
+	"github.com/coreos/go-oidc/v3/oidc"
 	"github.com/gin-gonic/gin"
 	"golang.org/x/oauth2"
 )
@@ -20,17 +21,25 @@ type AuthHandler struct {
 	authClient   *auth.Client
 	authStore    store.AuthStore
 	sessionStore store.SessionStore
+	maxLifetime  time.Duration
+	secureCookie bool
 }
 
+// NewAuthHandler creates the login handler, the session cookie is kept for the max lifetime of the sessions.
+// A secure session cookie is only sent with https.
 func NewAuthHandler(
 	authClient *auth.Client,
 	authStore store.AuthStore,
 	sessionStore store.SessionStore,
+	maxLifetime time.Duration,
+	secureCookie bool,
 ) *AuthHandler {
 	return &AuthHandler{
 		authClient:   authClient,
 		authStore:    authStore,
 		sessionStore: sessionStore,
+		maxLifetime:  maxLifetime,
+		secureCookie: secureCookie,
 	}
 }
 
@@ -44,8 +53,9 @@ func generateRandomSecureString() (string, error) {
 }
 
 // LoginHandler initiates the OAuth2 authorization code flow with dex.
-// It generates a secure state parameter to prevent CSRF attacks and stores it
-// in Redis for later verification during the callback phase.
+// It generates a secure state parameter to prevent CSRF attacks, a PKCE code verifier
+// to bind the authorization code to this login and a nonce to bind the ID token to it.
+// They are stored in the AuthStore for later verification during the callback phase.
 //
 // Returns:
 // - 302: Redirects to dex login page
@@ -56,18 +66,25 @@ func (a *AuthHandler) LoginHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate state"})
 		return
 	}
+	nonce, err := generateRandomSecureString()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate nonce"})
+		return
+	}
+	verifier := oauth2.GenerateVerifier()
 
 	// Store state in session for later verification
-	if err = a.authStore.SetState(c, state); err != nil {
+	if err = a.authStore.SetState(c, state, store.AuthData{CodeVerifier: verifier, Nonce: nonce}); err != nil {
 		log.Printf("failed to set state in Redis: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save session"})
 		return
 	}
-	// Build authentication URL
+	// Build authentication URL, the configured scopes are added by the oauth2 config
 	authURL := a.authClient.Oauth.AuthCodeURL(
 		state,
 		oauth2.SetAuthURLParam("response_type", "code"),
-		oauth2.SetAuthURLParam("scope", "openid profile email groups"),
+		oauth2.S256ChallengeOption(verifier),
+		oidc.Nonce(nonce),
 	)
 
 	// Redirect to dex login page
@@ -75,17 +92,20 @@ func (a *AuthHandler) LoginHandler(c *gin.Context) {
 }
 
 func (a *AuthHandler) CallbackHandler(c *gin.Context) {
-	if err := a.validateStateSession(c); err != nil {
+	authData, err := a.validateStateSession(c)
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to validate state session"})
 		return
 	}
-	oauthToken, err := a.tokenExchange(c)
+	oauthToken, err := a.tokenExchange(c, authData.CodeVerifier)
 	if err != nil {
+		log.Printf("failed to exchange the authorization code: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to exchange token"})
 		return
 	}
-	userInfo, err := a.validateAndGetClaimsIDToken(c, oauthToken)
+	userInfo, rawIDToken, err := a.validateAndGetClaimsIDToken(c, oauthToken, authData.Nonce)
 	if err != nil {
+		log.Printf("failed to validate the id token: %v", err)
 		c.JSON(http.StatusInternalServerError,
 			gin.H{"error": "Failed to validate and get claims id token"})
 		return
@@ -100,7 +120,10 @@ func (a *AuthHandler) CallbackHandler(c *gin.Context) {
 
 	// Create session data
 	sessionData := store.SessionData{
-		AccessToken: oauthToken.AccessToken,
+		AccessToken:  oauthToken.AccessToken,
+		RefreshToken: oauthToken.RefreshToken,
+		IDToken:      rawIDToken,
+		Expiry:       oauthToken.Expiry,
 		UserInfo: store.UserInfo{
 			Username: userInfo.Username,
 			Email:    userInfo.Email,
@@ -113,18 +136,17 @@ func (a *AuthHandler) CallbackHandler(c *gin.Context) {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store session"})
 		return
 	}
-	// Set SameSite attribute
-	// Note: Gin handles SameSite through the Config struct
-	//c.SetSameSite(http.SameSiteStrictMode)
+	// Lax, so the session cookie isn't sent with posts of other sites
+	c.SetSameSite(http.SameSiteLaxMode)
 	// Set secure session cookie using Gin's methods
 	c.SetCookie(
-		"session_id", // name
-		sessionID,    // value
-		3600,         // maxAge in seconds
-		"/",          // path
-		"",           // domain (empty means default to current domain)
-		false,        // secure (HTTPS only)
-		true,         // httpOnly (prevents JavaScript access)
+		"session_id",                 // name
+		sessionID,                    // value
+		int(a.maxLifetime.Seconds()), // maxAge in seconds
+		"/",                          // path
+		"",                           // domain (empty means default to current domain)
+		a.secureCookie,               // secure (HTTPS only)
+		true,                         // httpOnly (prevents JavaScript access)
 	)
 
 	// Redirect to dashboard using Gin's redirect method
@@ -133,69 +155,102 @@ func (a *AuthHandler) CallbackHandler(c *gin.Context) {
 }
 
 type oidcClaims struct {
-	Email    string   `json:"email"`
-	Username string   `json:"profile"`
-	Groups   []string `json:"groups"`
+	Email    string
+	Username string
+	Groups   []string
 }
 
-// ValidateIDToken verifies the id token from the oauth2token
+// ValidateIDToken verifies the id token from the oauth2token and its nonce, and returns its claims and the raw id token
 func (a *AuthHandler) validateAndGetClaimsIDToken(
-	c *gin.Context, oauth2Token *oauth2.Token) (*oidcClaims, error) {
+	c *gin.Context, oauth2Token *oauth2.Token, nonce string) (*oidcClaims, string, error) {
 	// Get and validate the ID token - this proves the user's identity
 	rawIDToken, ok := oauth2Token.Extra("id_token").(string)
 	if !ok {
-		return nil, errors.New("No ID token found")
+		return nil, "", errors.New("No ID token found")
 	}
 	// Verify the ID token
-	idToken, err := a.authClient.OIDC.Verify(c.Request.Context(), rawIDToken)
+	idToken, err := a.authClient.OIDC.Verify(a.authClient.Context(c.Request.Context()), rawIDToken)
 	if err != nil {
-		return nil, errors.New("Failed to verify ID token")
+		return nil, "", errors.New("Failed to verify ID token")
+	}
+	// the nonce proves that the ID token was issued for the login of this browser
+	if idToken.Nonce != nonce {
+		return nil, "", errors.New("ID token nonce mismatch")
+	}
+	var raw map[string]interface{}
+	if err := idToken.Claims(&raw); err != nil {
+		return nil, "", errors.New("Failed to get user info")
 	}
 	claims := oidcClaims{}
-	log.Println("IdToken: ", idToken)
-	if err := idToken.Claims(&claims); err != nil {
-		return nil, errors.New("Failed to get user info")
+	claims.Email, _ = raw["email"].(string)
+	claims.Username, claims.Groups = a.authClient.UserInfo(raw)
+	return &claims, rawIDToken, nil
+}
+
+// LogoutHandler deletes the session and the session cookie. It has to be posted, so other sites can't log out
+// the user with a link. If the provider supports RP-initiated logout, the user is redirected to it to end
+// the session at the provider as well, otherwise to the logged-out page.
+func (a *AuthHandler) LogoutHandler(c *gin.Context) {
+	var idToken string
+	if sessionID, err := c.Cookie("session_id"); err == nil {
+		if sessionData, err := a.sessionStore.Get(c, sessionID); err == nil {
+			idToken = sessionData.IDToken
+			log.Printf("User %s logged out", sessionData.UserInfo.Username)
+		}
+		if err := a.sessionStore.Delete(c, sessionID); err != nil {
+			log.Printf("Warning: failed to delete the session: %v", err)
+		}
 	}
-	return &claims, nil
+	c.SetCookie("session_id", "", -1, "/", "", a.secureCookie, true)
+
+	// see other, so the browser follows the redirects with GET
+	if endSessionURL := a.authClient.EndSessionURL(idToken); endSessionURL != "" {
+		c.Redirect(http.StatusSeeOther, endSessionURL)
+		return
+	}
+	c.Redirect(http.StatusSeeOther, "/auth/logged-out")
 }
 
-func (a *AuthHandler) tokenExchange(c *gin.Context) (*oauth2.Token, error) {
+// LoggedOutHandler shows the login page with the logged-out message.
+func (a *AuthHandler) LoggedOutHandler(c *gin.Context) {
+	c.HTML(http.StatusOK, "login.html", gin.H{"LoggedOut": true})
+}
+
+// tokenExchange exchanges the authorization code, the code verifier proves that this client started the login.
+func (a *AuthHandler) tokenExchange(c *gin.Context, codeVerifier string) (*oauth2.Token, error) {
 	authorizationCode := c.Query("code")
 	if authorizationCode == "" {
 		return nil, errors.New("authorizationCode is required")
 	}
 	opts := []oauth2.AuthCodeOption{
 		oauth2.SetAuthURLParam("grant_type", "authorization_code"),
+		oauth2.VerifierOption(codeVerifier),
 	}
-	oauth2Token, err := a.authClient.Oauth.Exchange(c, authorizationCode, opts...)
+	oauth2Token, err := a.authClient.Oauth.Exchange(a.authClient.Context(c), authorizationCode, opts...)
 	if err != nil {
 		return nil, err
 	}
 	return oauth2Token, nil
 }
 
-func (a *AuthHandler) validateStateSession(c *gin.Context) error {
+// validateStateSession returns the stored data of the login of the state parameter, each state can be used once.
+func (a *AuthHandler) validateStateSession(c *gin.Context) (*store.AuthData, error) {
 	// Get state from callback parameters
 	stateParam := c.Query("state")
 	if stateParam == "" {
-		return errors.New("missing state parameter in callback")
+		return nil, errors.New("missing state parameter in callback")
 	}
 
-	// Retrieve stored state from Redis
-	storedState, err := a.authStore.GetState(c, stateParam)
+	// Retrieve stored state, it is only found for the state parameter
+	authData, err := a.authStore.GetState(c, stateParam)
 	if err != nil {
-		return fmt.Errorf("failed to retrieve stored state: %w", err)
-	}
-
-	// Validate state match
-	if storedState != stateParam {
-		return errors.New("state parameter mismatch")
+		return nil, fmt.Errorf("failed to retrieve stored state: %w", err)
 	}
 
 	// Clean up used state from store
-	if err = a.authStore.DeleteState(c, storedState); err != nil {
+	if err = a.authStore.DeleteState(c, stateParam); err != nil {
 		log.Printf("Warning: failed to delete used state: %v", err)
 	}
 
-	return nil
+	return authData, nil
 }
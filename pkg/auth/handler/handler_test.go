@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"time"
+
+	auth "github.com/gattma/sealed-secrets-web/pkg/auth/dex"
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/test"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthHandler", func() {
+	var (
+		provider *test.OIDCProvider
+		states   *store.MemoryAuthManager
+		sessions *store.MemorySessionManager
+		router   *gin.Engine
+	)
+
+	BeforeEach(func() {
+		provider = test.NewOIDCProvider()
+		DeferCleanup(provider.Close)
+	})
+
+	// the client reads the discovery of the provider, so the provider can be changed in BeforeEach
+	JustBeforeEach(func() {
+		client, err := auth.New(context.Background(), &auth.Config{
+			IssuerURL:    provider.URL(),
+			ClientID:     test.OIDCClientID,
+			ClientSecret: test.OIDCClientSecret,
+			RedirectURL:  "http://localhost/auth/callback",
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		states = store.NewAuthMemoryManager()
+		sessions = store.NewSessionMemoryManager(0)
+		h := NewAuthHandler(client, states, sessions, 8*time.Hour, true)
+
+		router = gin.New()
+		router.LoadHTMLFiles("../../../templates/login.html")
+		router.GET("/auth/login", h.LoginHandler)
+		router.GET("/auth/callback", h.CallbackHandler)
+		router.POST("/auth/logout", h.LogoutHandler)
+		router.GET("/auth/logged-out", h.LoggedOutHandler)
+	})
+
+	// login follows the redirects of the browser from the login to the provider and back to the callback,
+	// tamper changes the authorization request before it is sent to the provider.
+	login := func(tamper func(params url.Values)) (*httptest.ResponseRecorder, *url.URL) {
+		w := httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/auth/login", nil)
+		router.ServeHTTP(w, req)
+		Ω(w.Code).Should(Equal(http.StatusTemporaryRedirect))
+		authURL, err := url.Parse(w.Header().Get("Location"))
+		Ω(err).ShouldNot(HaveOccurred())
+		if tamper != nil {
+			params := authURL.Query()
+			tamper(params)
+			authURL.RawQuery = params.Encode()
+		}
+
+		noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}}
+		resp, err := noRedirect.Get(authURL.String())
+		Ω(err).ShouldNot(HaveOccurred())
+		defer resp.Body.Close()
+		Ω(resp.StatusCode).Should(Equal(http.StatusFound))
+		callbackURL, err := url.Parse(resp.Header.Get("Location"))
+		Ω(err).ShouldNot(HaveOccurred())
+
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest(http.MethodGet, callbackURL.RequestURI(), nil)
+		router.ServeHTTP(w, req)
+		return w, authURL
+	}
+
+	Context("LoginHandler", func() {
+		It("sends the state, a S256 code challenge and a nonce", func() {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/auth/login", nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusTemporaryRedirect))
+			authURL, err := url.Parse(w.Header().Get("Location"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(authURL.Scheme + "://" + authURL.Host + authURL.Path).Should(Equal(provider.URL() + "/auth"))
+
+			params := authURL.Query()
+			Ω(params.Get("code_challenge_method")).Should(Equal("S256"))
+			Ω(params.Get("code_challenge")).ShouldNot(BeEmpty())
+			Ω(params.Get("nonce")).ShouldNot(BeEmpty())
+
+			stored, err := states.GetState(context.Background(), params.Get("state"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(stored.Nonce).Should(Equal(params.Get("nonce")))
+			digest := sha256.Sum256([]byte(stored.CodeVerifier))
+			Ω(params.Get("code_challenge")).Should(Equal(base64.RawURLEncoding.EncodeToString(digest[:])))
+		})
+	})
+
+	Context("CallbackHandler", func() {
+		It("stores the tokens in the session", func() {
+			w, _ := login(nil)
+			Ω(w.Code).Should(Equal(http.StatusTemporaryRedirect))
+			Ω(w.Header().Get("Location")).Should(Equal("/dashboard"))
+
+			var sessionCookie *http.Cookie
+			for _, c := range w.Result().Cookies() {
+				if c.Name == "session_id" {
+					sessionCookie = c
+				}
+			}
+			Ω(sessionCookie).ShouldNot(BeNil())
+			Ω(sessionCookie.MaxAge).Should(Equal(int((8 * time.Hour).Seconds())))
+			Ω(sessionCookie.Secure).Should(BeTrue())
+			Ω(sessionCookie.SameSite).Should(Equal(http.SameSiteLaxMode))
+
+			// gin escapes the cookie value
+			sessionID, err := url.QueryUnescape(sessionCookie.Value)
+			Ω(err).ShouldNot(HaveOccurred())
+			session, err := sessions.Get(context.Background(), sessionID)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(session.AccessToken).ShouldNot(BeEmpty())
+			Ω(session.RefreshToken).Should(HavePrefix("refresh-"))
+			Ω(session.IDToken).ShouldNot(BeEmpty())
+			Ω(session.Expiry).Should(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+			Ω(session.UserInfo.Username).Should(Equal("jane"))
+			Ω(session.UserInfo.Groups).Should(Equal([]string{"dev"}))
+
+			requests := provider.TokenRequests()
+			Ω(requests).Should(HaveLen(1))
+			Ω(requests[0].Get("code_verifier")).ShouldNot(BeEmpty())
+		})
+
+		It("can use a state only once", func() {
+			w, authURL := login(nil)
+			Ω(w.Code).Should(Equal(http.StatusTemporaryRedirect))
+
+			w = httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/auth/callback?code=code&state="+authURL.Query().Get("state"), nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusInternalServerError))
+			Ω(w.Body.String()).Should(ContainSubstring("Failed to validate state session"))
+		})
+
+		It("rejects a code issued for another code challenge", func() {
+			w, _ := login(func(params url.Values) {
+				digest := sha256.Sum256([]byte("attacker"))
+				params.Set("code_challenge", base64.RawURLEncoding.EncodeToString(digest[:]))
+			})
+			Ω(w.Code).Should(Equal(http.StatusInternalServerError))
+			Ω(w.Body.String()).Should(ContainSubstring("Failed to exchange token"))
+		})
+
+		It("rejects an ID token with another nonce", func() {
+			w, _ := login(func(params url.Values) {
+				params.Set("nonce", "attacker")
+			})
+			Ω(w.Code).Should(Equal(http.StatusInternalServerError))
+			Ω(w.Body.String()).Should(ContainSubstring("Failed to validate and get claims id token"))
+		})
+
+		It("rejects an ID token without nonce", func() {
+			w, _ := login(func(params url.Values) {
+				params.Del("nonce")
+			})
+			Ω(w.Code).Should(Equal(http.StatusInternalServerError))
+			Ω(w.Body.String()).Should(ContainSubstring("Failed to validate and get claims id token"))
+		})
+
+		It("rejects an unknown state", func() {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/auth/callback?state=unknown&code=code", nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusInternalServerError))
+			Ω(w.Body.String()).Should(ContainSubstring("Failed to validate state session"))
+			Ω(provider.TokenRequests()).Should(BeEmpty())
+		})
+	})
+
+	Context("LogoutHandler", func() {
+		logout := func() *httptest.ResponseRecorder {
+			Ω(sessions.Set(context.Background(), "id", store.SessionData{
+				IDToken:  "id-token",
+				UserInfo: store.UserInfo{Username: "jane"},
+			})).Should(Succeed())
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodPost, "/auth/logout", nil)
+			req.AddCookie(&http.Cookie{Name: "session_id", Value: "id"})
+			router.ServeHTTP(w, req)
+
+			_, err := sessions.Get(context.Background(), "id")
+			Ω(err).Should(MatchError(store.ErrSessionNotFound))
+			Ω(w.Header().Get("Set-Cookie")).Should(ContainSubstring("session_id=; Path=/; Max-Age=0"))
+			return w
+		}
+
+		It("logs out at the provider", func() {
+			w := logout()
+			Ω(w.Code).Should(Equal(http.StatusSeeOther))
+			location, err := url.Parse(w.Header().Get("Location"))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(location.Scheme + "://" + location.Host + location.Path).Should(Equal(provider.EndSessionEndpoint()))
+			Ω(location.Query().Get("id_token_hint")).Should(Equal("id-token"))
+			Ω(location.Query().Get("post_logout_redirect_uri")).Should(Equal("http://localhost/auth/logged-out"))
+			Ω(location.Query().Get("client_id")).Should(Equal(test.OIDCClientID))
+		})
+
+		Context("without end session endpoint", func() {
+			BeforeEach(func() {
+				provider.DisableEndSession = true
+			})
+
+			It("redirects to the logged-out page", func() {
+				w := logout()
+				Ω(w.Code).Should(Equal(http.StatusSeeOther))
+				Ω(w.Header().Get("Location")).Should(Equal("/auth/logged-out"))
+			})
+		})
+
+		It("does not log out with a link", func() {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/auth/logout", nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusNotFound))
+		})
+
+		It("shows the logged-out page", func() {
+			w := httptest.NewRecorder()
+			req, _ := http.NewRequest(http.MethodGet, "/auth/logged-out", nil)
+			router.ServeHTTP(w, req)
+			Ω(w.Code).Should(Equal(http.StatusOK))
+			Ω(w.Body.String()).Should(ContainSubstring("You have been logged out"))
+			Ω(w.Body.String()).Should(ContainSubstring(`href="/auth/login"`))
+		})
+	})
+})
@@ -4,6 +4,8 @@ import (
 	"context"
 	"log"
 	"net/http"
+	"sync"
+	"time"
 
 	"github.com/gattma/sealed-secrets-web/pkg/auth/dex"
 	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
@@ -12,20 +14,32 @@ import (
 	"github.com/gin-gonic/gin"
 )
 
+const (
+	// refreshBefore is the time before the expiry of the access token, from which on it is refreshed.
+	refreshBefore = time.Minute
+	// refreshReuse is the time the result of a refresh is reused for requests still sending the previous tokens.
+	refreshReuse = 30 * time.Second
+)
+
 type AuthMiddleware struct {
 	authClient   *auth.Client
 	sessionStore store.SessionStore
 	clientID     string
+	maxLifetime  time.Duration
+	refreshes    refreshes
 }
 
-// NewAuthMiddleware creates a new authentication middleware with OIDC verification
+// NewAuthMiddleware creates a new authentication middleware with OIDC verification.
+// Sessions older than the max lifetime are rejected, even if they are still used.
 func NewAuthMiddleware(c context.Context,
 	authClient *auth.Client,
 	sessionStore store.SessionStore,
+	maxLifetime time.Duration,
 ) *AuthMiddleware {
 	return &AuthMiddleware{
 		authClient:   authClient,
 		sessionStore: sessionStore,
+		maxLifetime:  maxLifetime,
 	}
 }
 func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
@@ -48,6 +62,18 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 			c.Abort()
 			return
 		}
+		if m.maxLifetime > 0 && time.Since(sessionData.CreatedAt) > m.maxLifetime {
+			log.Printf("Session of user %s exceeded the max lifetime", sessionData.UserInfo.Username)
+			m.sessionStore.Delete(c, sessionID)
+			c.SetCookie("session_id", "", -1, "/", "", true, true)
+			c.Redirect(http.StatusTemporaryRedirect, "/")
+			c.Abort()
+			return
+		}
+		if err := m.refresh(c, sessionID, sessionData); err != nil {
+			// the session is still valid until the access token expires
+			log.Printf("Failed to refresh the access token of user %s: %v", sessionData.UserInfo.Username, err)
+		}
 		// Verify the access token using the OIDC provider
 		token, err := m.authClient.Provider.Verifier(&oidc.Config{
 			SkipClientIDCheck: true, // Access tokens don't require client ID check
@@ -75,3 +101,103 @@ func (m *AuthMiddleware) RequireAuth() gin.HandlerFunc {
 		c.Next()
 	}
 }
+
+// refresh renews the access token with the refresh token, if it expires soon.
+// The session is updated with the new tokens, as the provider may rotate the refresh token.
+// The refreshes of a session are serialized, so a refresh token is only redeemed once by concurrent requests.
+func (m *AuthMiddleware) refresh(c *gin.Context, sessionID string, sessionData *store.SessionData) error {
+	if !needsRefresh(sessionData) {
+		return nil
+	}
+	r := m.refreshes.acquire(sessionID)
+	defer m.refreshes.release(r)
+
+	// requests which were sent with the session before a concurrent refresh (cookie store) get its result
+	if r.redeemed == sessionData.RefreshToken && time.Now().Before(r.expires) {
+		*sessionData = r.result
+		return m.sessionStore.Set(c, sessionID, *sessionData)
+	}
+	// the session may have been refreshed by a concurrent request or another replica
+	current, err := m.sessionStore.Get(c, sessionID)
+	if err != nil {
+		return err
+	}
+	if current.RefreshToken != sessionData.RefreshToken || !needsRefresh(current) {
+		*sessionData = *current
+		return nil
+	}
+
+	token, err := m.authClient.Refresh(c, current.RefreshToken)
+	if err != nil {
+		return err
+	}
+	current.AccessToken = token.AccessToken
+	current.Expiry = token.Expiry
+	if token.RefreshToken != "" {
+		current.RefreshToken = token.RefreshToken
+	}
+	if idToken, ok := token.Extra("id_token").(string); ok && idToken != "" {
+		current.IDToken = idToken
+	}
+	if err := m.sessionStore.Set(c, sessionID, *current); err != nil {
+		return err
+	}
+	r.redeemed, r.result, r.expires = sessionData.RefreshToken, *current, time.Now().Add(refreshReuse)
+	*sessionData = *current
+	return nil
+}
+
+func needsRefresh(sessionData *store.SessionData) bool {
+	return sessionData.RefreshToken != "" && !sessionData.Expiry.IsZero() &&
+		time.Until(sessionData.Expiry) <= refreshBefore
+}
+
+// refreshes serializes the refreshes per session.
+type refreshes struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionRefresh
+}
+
+// sessionRefresh is the lock of a session, it keeps the result of the last refresh for a short time.
+type sessionRefresh struct {
+	sync.Mutex
+	users int
+	// redeemed is the refresh token the result was refreshed with
+	redeemed string
+	result   store.SessionData
+	expires  time.Time
+}
+
+// acquire locks the refreshes of the session.
+func (r *refreshes) acquire(sessionID string) *sessionRefresh {
+	r.mu.Lock()
+	if r.sessions == nil {
+		r.sessions = map[string]*sessionRefresh{}
+	}
+	s, ok := r.sessions[sessionID]
+	if !ok {
+		s = &sessionRefresh{}
+		r.sessions[sessionID] = s
+	}
+	s.users++
+	r.mu.Unlock()
+
+	s.Lock()
+	return s
+}
+
+// release unlocks the refreshes of the session. Unused sessions without a recent refresh are forgotten,
+// their fields can be read as nobody holds their lock.
+func (r *refreshes) release(s *sessionRefresh) {
+	s.Unlock()
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s.users--
+	now := time.Now()
+	for id, other := range r.sessions {
+		if other.users == 0 && now.After(other.expires) {
+			delete(r.sessions, id)
+		}
+	}
+}
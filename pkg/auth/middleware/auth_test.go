@@ -0,0 +1,170 @@
+package middleware
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"time"
+
+	auth "github.com/gattma/sealed-secrets-web/pkg/auth/dex"
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/test"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("AuthMiddleware", func() {
+	var (
+		provider *test.OIDCProvider
+		sessions *store.MemorySessionManager
+		router   *gin.Engine
+		w        *httptest.ResponseRecorder
+		session  *store.SessionData
+		m        *AuthMiddleware
+	)
+
+	request := func() {
+		w = httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		req.AddCookie(&http.Cookie{Name: "session_id", Value: "id"})
+		router.ServeHTTP(w, req)
+	}
+	storeSession := func(lifetime time.Duration, createdAt time.Time) {
+		Ω(sessions.Set(context.Background(), "id", store.SessionData{
+			AccessToken:  provider.Token("subject", lifetime),
+			RefreshToken: provider.RefreshToken(),
+			Expiry:       time.Now().Add(lifetime),
+			UserInfo:     store.UserInfo{Username: "jane"},
+			CreatedAt:    createdAt,
+		})).Should(Succeed())
+	}
+
+	BeforeEach(func() {
+		provider = test.NewOIDCProvider()
+		DeferCleanup(provider.Close)
+		client, err := auth.New(context.Background(), &auth.Config{
+			IssuerURL:    provider.URL(),
+			ClientID:     test.OIDCClientID,
+			ClientSecret: test.OIDCClientSecret,
+		})
+		Ω(err).ShouldNot(HaveOccurred())
+		sessions = store.NewSessionMemoryManager(0)
+		m = NewAuthMiddleware(context.Background(), client, sessions, time.Hour)
+
+		session = nil
+		router = gin.New()
+		router.GET("/", m.RequireAuth(), func(c *gin.Context) {
+			s, _ := c.Get("user_session")
+			session = s.(*store.SessionData)
+			c.Status(http.StatusOK)
+		})
+		router.GET("/concurrent", m.RequireAuth(), func(c *gin.Context) {
+			c.Status(http.StatusOK)
+		})
+	})
+
+	It("redirects to the login page without session", func() {
+		w = httptest.NewRecorder()
+		req, _ := http.NewRequest(http.MethodGet, "/", nil)
+		router.ServeHTTP(w, req)
+		Ω(w.Code).Should(Equal(http.StatusTemporaryRedirect))
+		Ω(w.Header().Get("Location")).Should(Equal("/"))
+	})
+
+	It("accepts a valid session without refresh", func() {
+		storeSession(time.Hour, time.Now())
+		request()
+		Ω(w.Code).Should(Equal(http.StatusOK))
+		Ω(session.UserInfo.Username).Should(Equal("jane"))
+		Ω(provider.TokenRequests()).Should(BeEmpty())
+	})
+
+	It("refreshes an access token that expires soon", func() {
+		storeSession(30*time.Second, time.Now())
+		old, err := sessions.Get(context.Background(), "id")
+		Ω(err).ShouldNot(HaveOccurred())
+
+		request()
+		Ω(w.Code).Should(Equal(http.StatusOK))
+		requests := provider.TokenRequests()
+		Ω(requests).Should(HaveLen(1))
+		Ω(requests[0].Get("grant_type")).Should(Equal("refresh_token"))
+		Ω(requests[0].Get("refresh_token")).Should(Equal(old.RefreshToken))
+
+		refreshed, err := sessions.Get(context.Background(), "id")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(refreshed.AccessToken).ShouldNot(Equal(old.AccessToken))
+		Ω(refreshed.RefreshToken).ShouldNot(Equal(old.RefreshToken))
+		Ω(refreshed.IDToken).ShouldNot(BeEmpty())
+		Ω(refreshed.Expiry).Should(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+		Ω(refreshed.CreatedAt).Should(BeTemporally("==", old.CreatedAt))
+	})
+
+	It("redeems the refresh token once for concurrent requests", func() {
+		storeSession(30*time.Second, time.Now())
+
+		var wg sync.WaitGroup
+		codes := make([]int, 10)
+		for i := range codes {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				rec := httptest.NewRecorder()
+				req, _ := http.NewRequest(http.MethodGet, "/concurrent", nil)
+				req.AddCookie(&http.Cookie{Name: "session_id", Value: "id"})
+				router.ServeHTTP(rec, req)
+				codes[i] = rec.Code
+			}()
+		}
+		wg.Wait()
+
+		Ω(codes).Should(HaveEach(http.StatusOK))
+		Ω(provider.TokenRequests()).Should(HaveLen(1))
+	})
+
+	It("reuses the refresh for requests with the previous tokens", func() {
+		storeSession(30*time.Second, time.Now())
+		previous, err := sessions.Get(context.Background(), "id")
+		Ω(err).ShouldNot(HaveOccurred())
+		request()
+		Ω(w.Code).Should(Equal(http.StatusOK))
+
+		// like a request of the cookie store, which was sent before the refreshed cookie was set
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		Ω(m.refresh(c, "id", previous)).Should(Succeed())
+		Ω(provider.TokenRequests()).Should(HaveLen(1))
+		Ω(previous.AccessToken).Should(Equal(session.AccessToken))
+		Ω(previous.RefreshToken).Should(Equal(session.RefreshToken))
+	})
+
+	It("uses the session refreshed by another replica", func() {
+		storeSession(30*time.Second, time.Now())
+		stale, err := sessions.Get(context.Background(), "id")
+		Ω(err).ShouldNot(HaveOccurred())
+		storeSession(time.Hour, stale.CreatedAt)
+
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		Ω(m.refresh(c, "id", stale)).Should(Succeed())
+		Ω(provider.TokenRequests()).Should(BeEmpty())
+		Ω(stale.Expiry).Should(BeTemporally("~", time.Now().Add(time.Hour), time.Minute))
+	})
+
+	It("rejects an expired access token that can't be refreshed", func() {
+		storeSession(-time.Minute, time.Now())
+		provider.RevokeRefreshTokens()
+		request()
+		Ω(w.Code).Should(Equal(http.StatusTemporaryRedirect))
+		_, err := sessions.Get(context.Background(), "id")
+		Ω(err).Should(MatchError(store.ErrSessionNotFound))
+	})
+
+	It("rejects a session exceeding the max lifetime", func() {
+		storeSession(time.Hour, time.Now().Add(-2*time.Hour))
+		request()
+		Ω(w.Code).Should(Equal(http.StatusTemporaryRedirect))
+		_, err := sessions.Get(context.Background(), "id")
+		Ω(err).Should(MatchError(store.ErrSessionNotFound))
+	})
+})
@@ -0,0 +1,95 @@
+package middleware
+
+import (
+	"log"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HeaderMiddleware authenticates the users with the headers set by an authenticating reverse proxy
+// like oauth2-proxy or Pomerium. The headers are only trusted if the request comes directly from a trusted proxy.
+type HeaderMiddleware struct {
+	userHeader     string
+	groupsHeader   string
+	emailHeader    string
+	trustedProxies []*net.IPNet
+}
+
+// NewHeaderMiddleware creates a new authentication middleware trusting the user headers of the trusted proxies.
+// It fails without trusted proxies, the headers of all other clients are rejected.
+func NewHeaderMiddleware(cfg config.HeaderAuth) (*HeaderMiddleware, error) {
+	proxies, err := cfg.Proxies()
+	if err != nil {
+		return nil, err
+	}
+	m := &HeaderMiddleware{
+		userHeader:     cfg.UserHeader,
+		groupsHeader:   cfg.GroupsHeader,
+		emailHeader:    cfg.EmailHeader,
+		trustedProxies: proxies,
+	}
+	if m.userHeader == "" {
+		m.userHeader = config.DefaultUserHeader
+	}
+	if m.groupsHeader == "" {
+		m.groupsHeader = config.DefaultGroupsHeader
+	}
+	if m.emailHeader == "" {
+		m.emailHeader = config.DefaultEmailHeader
+	}
+	return m, nil
+}
+
+func (m *HeaderMiddleware) RequireAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		// the direct peer is checked, forwarded-for headers could be set by anybody
+		if !m.trusted(c.RemoteIP()) {
+			log.Printf("Rejected user headers from untrusted address %s\n", c.RemoteIP())
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Request is not from a trusted proxy"})
+			return
+		}
+
+		username := c.GetHeader(m.userHeader)
+		if username == "" {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Not authenticated"})
+			return
+		}
+
+		var groups []string
+		for _, g := range strings.Split(c.GetHeader(m.groupsHeader), ",") {
+			if g = strings.TrimSpace(g); g != "" {
+				groups = append(groups, g)
+			}
+		}
+
+		// Store the session in the context like the OIDC middleware
+		c.Set("user_session", &store.SessionData{
+			UserInfo: store.UserInfo{
+				Username: username,
+				Email:    c.GetHeader(m.emailHeader),
+				Groups:   groups,
+			},
+		})
+		c.Next()
+	}
+}
+
+// trusted returns true if the address is in the network of a trusted proxy.
+func (m *HeaderMiddleware) trusted(addr string) bool {
+	ip := net.ParseIP(addr)
+	if ip == nil {
+		return false
+	}
+	for _, n := range m.trustedProxies {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package middleware
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("HeaderMiddleware", func() {
+	var (
+		router  *gin.Engine
+		w       *httptest.ResponseRecorder
+		req     *http.Request
+		session *store.SessionData
+	)
+
+	setup := func(cfg config.HeaderAuth) {
+		m, err := NewHeaderMiddleware(cfg)
+		Ω(err).ShouldNot(HaveOccurred())
+		router = gin.New()
+		router.GET("/", m.RequireAuth(), func(c *gin.Context) {
+			s, _ := c.Get("user_session")
+			session = s.(*store.SessionData)
+			c.Status(http.StatusOK)
+		})
+	}
+
+	BeforeEach(func() {
+		session = nil
+		w = httptest.NewRecorder()
+		req, _ = http.NewRequest("GET", "/", nil)
+		req.RemoteAddr = "10.1.2.3:4711"
+		setup(config.HeaderAuth{TrustedProxies: []string{"10.0.0.0/8"}})
+	})
+
+	It("fills the user session from the default headers", func() {
+		req.Header.Set("X-Forwarded-User", "jane")
+		req.Header.Set("X-Forwarded-Groups", "dev, ops,")
+		req.Header.Set("X-Forwarded-Email", "jane@example.com")
+		router.ServeHTTP(w, req)
+		Ω(w.Code).Should(Equal(http.StatusOK))
+		Ω(session.UserInfo).Should(Equal(store.UserInfo{
+			Username: "jane",
+			Email:    "jane@example.com",
+			Groups:   []string{"dev", "ops"},
+		}))
+	})
+
+	It("uses the configured headers", func() {
+		setup(config.HeaderAuth{
+			UserHeader:     "X-Pomerium-Claim-User",
+			GroupsHeader:   "X-Pomerium-Claim-Groups",
+			EmailHeader:    "X-Pomerium-Claim-Email",
+			TrustedProxies: []string{"10.1.2.3"},
+		})
+		req.Header.Set("X-Forwarded-User", "mallory")
+		req.Header.Set("X-Pomerium-Claim-User", "jane")
+		req.Header.Set("X-Pomerium-Claim-Groups", "dev")
+		router.ServeHTTP(w, req)
+		Ω(w.Code).Should(Equal(http.StatusOK))
+		Ω(session.UserInfo.Username).Should(Equal("jane"))
+		Ω(session.UserInfo.Groups).Should(Equal([]string{"dev"}))
+	})
+
+	It("rejects requests without user", func() {
+		router.ServeHTTP(w, req)
+		Ω(w.Code).Should(Equal(http.StatusUnauthorized))
+		Ω(session).Should(BeNil())
+	})
+
+	It("rejects the headers of untrusted addresses", func() {
+		req.RemoteAddr = "192.168.1.10:4711"
+		req.Header.Set("X-Forwarded-User", "jane")
+		req.Header.Set("X-Forwarded-For", "10.1.2.3")
+		router.ServeHTTP(w, req)
+		Ω(w.Code).Should(Equal(http.StatusForbidden))
+		Ω(session).Should(BeNil())
+	})
+
+	It("fails without trusted proxies", func() {
+		_, err := NewHeaderMiddleware(config.HeaderAuth{})
+		Ω(err).Should(MatchError(config.ErrNoTrustedProxies))
+	})
+
+	It("fails for an invalid trusted proxy", func() {
+		_, err := NewHeaderMiddleware(config.HeaderAuth{TrustedProxies: []string{"not-an-ip"}})
+		Ω(err).Should(MatchError("invalid trusted proxy 'not-an-ip'"))
+	})
+})
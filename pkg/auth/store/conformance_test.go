@@ -0,0 +1,249 @@
+package store
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/redis/go-redis/v9"
+)
+
+// storeFixture is the setup of a store implementation for the conformance tests.
+type storeFixture struct {
+	sessions SessionStore
+	states   AuthStore
+	// ctx returns the context of the next request
+	ctx func() context.Context
+	// advance moves the clock of the stores forward, nil if the time can't be controlled
+	advance func(d time.Duration)
+}
+
+// fakeClock is the clock of the stores with controllable time.
+type fakeClock struct {
+	t time.Time
+}
+
+func (f *fakeClock) now() time.Time {
+	return f.t
+}
+
+func (f *fakeClock) advance(d time.Duration) {
+	f.t = f.t.Add(d)
+}
+
+// cookieJar passes the cookies of the previous response to the next request, like a browser.
+type cookieJar struct {
+	cookies map[string]*http.Cookie
+	last    *httptest.ResponseRecorder
+}
+
+func (j *cookieJar) ctx() context.Context {
+	if j.last != nil {
+		for _, c := range j.last.Result().Cookies() {
+			if c.MaxAge < 0 {
+				delete(j.cookies, c.Name)
+			} else {
+				j.cookies[c.Name] = c
+			}
+		}
+	}
+	j.last = httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(j.last)
+	c.Request = httptest.NewRequest(http.MethodGet, "/", nil)
+	for _, cookie := range j.cookies {
+		c.Request.AddCookie(cookie)
+	}
+	return c
+}
+
+// fakeToken returns a random token of the given length, like the JWTs of an identity provider.
+func fakeToken(length int) string {
+	b := make([]byte, length)
+	_, _ = rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)[:length]
+}
+
+// conformance runs the tests every store implementation has to pass.
+func conformance(newFixture func() storeFixture) {
+	var (
+		f         storeFixture
+		sessionID string
+		data      SessionData
+	)
+	BeforeEach(func() {
+		f = newFixture()
+		sessionID = uuid.NewString()
+		data = SessionData{
+			AccessToken: "token",
+			UserInfo:    UserInfo{Username: "jane", Email: "jane@example.com", Groups: []string{"dev", "ops"}},
+			CreatedAt:   time.Unix(1700000000, 0),
+		}
+	})
+	expectSession := func(actual *SessionData) {
+		Ω(actual.AccessToken).Should(Equal(data.AccessToken))
+		Ω(actual.UserInfo).Should(Equal(data.UserInfo))
+		Ω(actual.CreatedAt).Should(BeTemporally("==", data.CreatedAt))
+	}
+
+	Context("sessions", func() {
+		It("returns the stored session", func() {
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			actual, err := f.sessions.Get(f.ctx(), sessionID)
+			Ω(err).ShouldNot(HaveOccurred())
+			expectSession(actual)
+		})
+		It("replaces the stored session", func() {
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			data.AccessToken = "refreshed"
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			actual, err := f.sessions.Get(f.ctx(), sessionID)
+			Ω(err).ShouldNot(HaveOccurred())
+			expectSession(actual)
+		})
+		It("stores sessions with realistic token sizes", func() {
+			data.AccessToken, data.IDToken, data.RefreshToken = fakeToken(1500), fakeToken(1200), fakeToken(1200)
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			actual, err := f.sessions.Get(f.ctx(), sessionID)
+			Ω(err).ShouldNot(HaveOccurred())
+			expectSession(actual)
+			Ω(actual.IDToken).Should(Equal(data.IDToken))
+			Ω(actual.RefreshToken).Should(Equal(data.RefreshToken))
+
+			data.AccessToken, data.IDToken, data.RefreshToken = "token", "", ""
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			actual, err = f.sessions.Get(f.ctx(), sessionID)
+			Ω(err).ShouldNot(HaveOccurred())
+			expectSession(actual)
+		})
+		It("does not find an unknown session", func() {
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			_, err := f.sessions.Get(f.ctx(), uuid.NewString())
+			Ω(err).Should(MatchError(ErrSessionNotFound))
+		})
+		It("does not find a deleted session", func() {
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			Ω(f.sessions.Delete(f.ctx(), sessionID)).Should(Succeed())
+			_, err := f.sessions.Get(f.ctx(), sessionID)
+			Ω(err).Should(MatchError(ErrSessionNotFound))
+		})
+		It("does not find an expired session", func() {
+			if f.advance == nil {
+				Skip("the time of the store can't be controlled")
+			}
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			f.advance(DefaultSessionTTL)
+			_, err := f.sessions.Get(f.ctx(), sessionID)
+			Ω(err).Should(MatchError(ErrSessionNotFound))
+		})
+		It("renews the session on access", func() {
+			if f.advance == nil {
+				Skip("the time of the store can't be controlled")
+			}
+			Ω(f.sessions.Set(f.ctx(), sessionID, data)).Should(Succeed())
+			for range 3 {
+				f.advance(DefaultSessionTTL - time.Second)
+				_, err := f.sessions.Get(f.ctx(), sessionID)
+				Ω(err).ShouldNot(HaveOccurred())
+			}
+		})
+	})
+
+	Context("states", func() {
+		var (
+			state     string
+			stateData AuthData
+		)
+		BeforeEach(func() {
+			state = uuid.NewString()
+			stateData = AuthData{CodeVerifier: "verifier", Nonce: "nonce"}
+		})
+		It("returns the stored state", func() {
+			Ω(f.states.SetState(f.ctx(), state, stateData)).Should(Succeed())
+			Ω(f.states.GetState(f.ctx(), state)).Should(Equal(&stateData))
+		})
+		It("does not find an unknown state", func() {
+			Ω(f.states.SetState(f.ctx(), state, stateData)).Should(Succeed())
+			_, err := f.states.GetState(f.ctx(), uuid.NewString())
+			Ω(err).Should(MatchError(ErrStateNotFound))
+		})
+		It("does not find a deleted state", func() {
+			Ω(f.states.SetState(f.ctx(), state, stateData)).Should(Succeed())
+			Ω(f.states.DeleteState(f.ctx(), state)).Should(Succeed())
+			_, err := f.states.GetState(f.ctx(), state)
+			Ω(err).Should(MatchError(ErrStateNotFound))
+		})
+		It("does not find an expired state", func() {
+			if f.advance == nil {
+				Skip("the time of the store can't be controlled")
+			}
+			Ω(f.states.SetState(f.ctx(), state, stateData)).Should(Succeed())
+			f.advance(DefaultStateTTL)
+			_, err := f.states.GetState(f.ctx(), state)
+			Ω(err).Should(MatchError(ErrStateNotFound))
+		})
+		It("keeps states and sessions apart", func() {
+			Ω(f.states.SetState(f.ctx(), state, stateData)).Should(Succeed())
+			_, err := f.sessions.Get(f.ctx(), state)
+			Ω(err).Should(MatchError(ErrSessionNotFound))
+		})
+	})
+}
+
+var _ = Describe("Store conformance", func() {
+	Context("memory", func() {
+		conformance(func() storeFixture {
+			clock := &fakeClock{t: time.Now()}
+			sessions, states := NewSessionMemoryManager(DefaultSessionTTL), NewAuthMemoryManager()
+			sessions.sessions.now, states.states.now = clock.now, clock.now
+			return storeFixture{
+				sessions: sessions,
+				states:   states,
+				ctx:      context.Background,
+				advance:  clock.advance,
+			}
+		})
+	})
+
+	Context("cookie", func() {
+		conformance(func() storeFixture {
+			clock := &fakeClock{t: time.Now()}
+			key := []byte("0123456789abcdef0123456789abcdef")
+			sessions, err := NewSessionCookieManager(key, DefaultSessionTTL, true)
+			Ω(err).ShouldNot(HaveOccurred())
+			states, err := NewAuthCookieManager(key, true)
+			Ω(err).ShouldNot(HaveOccurred())
+			sessions.codec.now, states.codec.now = clock.now, clock.now
+			jar := &cookieJar{cookies: map[string]*http.Cookie{}}
+			return storeFixture{
+				sessions: sessions,
+				states:   states,
+				ctx:      jar.ctx,
+				advance:  clock.advance,
+			}
+		})
+	})
+
+	Context("redis", func() {
+		conformance(func() storeFixture {
+			addr := os.Getenv("REDIS_ADDR")
+			if addr == "" {
+				Skip("REDIS_ADDR is not set")
+			}
+			client := redis.NewClient(&redis.Options{Addr: addr})
+			DeferCleanup(client.Close)
+			return storeFixture{
+				sessions: NewSessionRedisManager(client, DefaultSessionTTL),
+				states:   NewAuthRedisManager(client),
+				ctx:      context.Background,
+			}
+		})
+	})
+})
@@ -0,0 +1,296 @@
+package store
+
+import (
+	"context"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// SessionCookie is the cookie of the encrypted session data.
+	SessionCookie = "session_data"
+	// StateCookie is the cookie of the encrypted login state.
+	StateCookie = "auth_state"
+)
+
+// errNoGinContext is returned if the cookie stores are not called with the gin context of the request.
+var errNoGinContext = errors.New("the cookie store requires the gin context of the request")
+
+// aeadCodec encrypts and authenticates values with AES-GCM.
+// The name of the value is authenticated as well, so a value can't be used in another cookie or field.
+type aeadCodec struct {
+	aead cipher.AEAD
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// cookieValue is the encrypted content of a cookie, expired values are rejected even if the browser still sends them.
+type cookieValue[T any] struct {
+	ID      string `json:"id"`
+	Value   T      `json:"value"`
+	Expires int64  `json:"expires"`
+}
+
+func newAEADCodec(key []byte, ttl time.Duration) (*aeadCodec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid encryption key: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return &aeadCodec{aead: aead, ttl: ttl, now: time.Now}, nil
+}
+
+func (cc *aeadCodec) encode(name string, plain []byte) (string, error) {
+	nonce := make([]byte, cc.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := cc.aead.Seal(nonce, nonce, plain, []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (cc *aeadCodec) decode(name, value string) ([]byte, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < cc.aead.NonceSize() {
+		return nil, errors.New("encrypted value too short")
+	}
+	nonce, ciphertext := sealed[:cc.aead.NonceSize()], sealed[cc.aead.NonceSize():]
+	return cc.aead.Open(nil, nonce, ciphertext, []byte(name))
+}
+
+const (
+	// maxCookieSize is the size of a cookie including its name and attributes, which all browsers accept.
+	maxCookieSize = 4096
+	// maxCookieChunks is the number of cookies a value can be split into. The cookies are sent with every request
+	// and have to fit into the header limits of the proxies in front of the service.
+	maxCookieChunks = 4
+)
+
+// errCookieTooLarge is returned if the encrypted value doesn't fit into maxCookieChunks cookies.
+var errCookieTooLarge = errors.New("the value is too large for the cookie store, use the redis or memory session store")
+
+// cookieCodec writes the values encrypted by the codec into cookies.
+type cookieCodec struct {
+	*aeadCodec
+	// secure restricts the cookies to https
+	secure bool
+}
+
+func newCookieCodec(key []byte, ttl time.Duration, secure bool) (*cookieCodec, error) {
+	codec, err := newAEADCodec(key, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return &cookieCodec{aeadCodec: codec, secure: secure}, nil
+}
+
+// chunkName returns the name of the i-th cookie of a value, the first one has the name of the value.
+func chunkName(name string, i int) string {
+	if i == 0 {
+		return name
+	}
+	return fmt.Sprintf("%s_%d", name, i)
+}
+
+func (cc *cookieCodec) cookie(name, value string, maxAge int) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		MaxAge:   maxAge,
+		HttpOnly: true,
+		Secure:   cc.secure,
+		SameSite: http.SameSiteLaxMode,
+	}
+}
+
+// write splits the value into as few cookies as needed, as browsers only accept cookies up to maxCookieSize.
+// The remaining cookies of a previous, larger value are deleted.
+func (cc *cookieCodec) write(c *gin.Context, name, value string) error {
+	maxAge := int(cc.ttl.Seconds())
+	chunkSize := maxCookieSize - len(cc.cookie(chunkName(name, maxCookieChunks-1), "", maxAge).String())
+	if len(value) > maxCookieChunks*chunkSize {
+		return fmt.Errorf("%s cookie of %d bytes: %w", name, len(value), errCookieTooLarge)
+	}
+	var chunks []string
+	for len(value) > chunkSize {
+		chunks, value = append(chunks, value[:chunkSize]), value[chunkSize:]
+	}
+	chunks = append(chunks, value)
+
+	for i, chunk := range chunks {
+		http.SetCookie(c.Writer, cc.cookie(chunkName(name, i), chunk, maxAge))
+	}
+	cc.expire(c, name, len(chunks))
+	return nil
+}
+
+// read joins the cookies of the value, it returns false if there is no cookie.
+func (cc *cookieCodec) read(c *gin.Context, name string) (string, bool) {
+	value, err := c.Cookie(name)
+	if err != nil {
+		return "", false
+	}
+	for i := 1; i < maxCookieChunks; i++ {
+		chunk, err := c.Cookie(chunkName(name, i))
+		if err != nil {
+			break
+		}
+		value += chunk
+	}
+	return value, true
+}
+
+// expire deletes the cookies of the value from the given chunk on. Only the first one is deleted unconditionally,
+// the others if the request has them.
+func (cc *cookieCodec) expire(c *gin.Context, name string, from int) {
+	for i := from; i < maxCookieChunks; i++ {
+		if i > 0 && c.Request != nil {
+			if _, err := c.Request.Cookie(chunkName(name, i)); err != nil {
+				continue
+			}
+		}
+		http.SetCookie(c.Writer, cc.cookie(chunkName(name, i), "", -1))
+	}
+}
+
+// setCookie writes the encrypted value to the response.
+func setCookie[T any](ctx context.Context, cc *cookieCodec, name, id string, value T) error {
+	c, ok := ctx.(*gin.Context)
+	if !ok {
+		return errNoGinContext
+	}
+	plain, err := json.Marshal(cookieValue[T]{ID: id, Value: value, Expires: cc.now().Add(cc.ttl).Unix()})
+	if err != nil {
+		return fmt.Errorf("failed to marshal cookie value: %w", err)
+	}
+	encoded, err := cc.encode(name, plain)
+	if err != nil {
+		return fmt.Errorf("failed to encrypt cookie value: %w", err)
+	}
+	return cc.write(c, name, encoded)
+}
+
+// getCookie returns the decrypted value of the request cookie, if it has the given id and is not expired.
+func getCookie[T any](ctx context.Context, cc *cookieCodec, name, id string) (T, bool, error) {
+	var v cookieValue[T]
+	c, ok := ctx.(*gin.Context)
+	if !ok {
+		return v.Value, false, errNoGinContext
+	}
+	encoded, ok := cc.read(c, name)
+	if !ok {
+		return v.Value, false, nil
+	}
+	plain, err := cc.decode(name, encoded)
+	if err != nil {
+		// tampered, or encrypted with another key
+		return v.Value, false, nil
+	}
+	if err := json.Unmarshal(plain, &v); err != nil {
+		return v.Value, false, nil
+	}
+	if v.ID != id || cc.now().Unix() >= v.Expires {
+		return v.Value, false, nil
+	}
+	return v.Value, true, nil
+}
+
+func deleteCookie(ctx context.Context, cc *cookieCodec, name string) error {
+	c, ok := ctx.(*gin.Context)
+	if !ok {
+		return errNoGinContext
+	}
+	cc.expire(c, name, 0)
+	return nil
+}
+
+// CookieSessionManager keeps the sessions in an encrypted cookie, so no server side store is needed.
+// It has to be called with the gin context of the request. The key must be 16, 24 or 32 bytes long
+// and the same for all replicas. Large sessions are split into several cookies.
+type CookieSessionManager struct {
+	codec *cookieCodec
+}
+
+// NewSessionCookieManager creates the cookie session store, sessions expire after the idle timeout.
+// Secure cookies are only sent with https.
+func NewSessionCookieManager(key []byte, idleTimeout time.Duration, secure bool) (*CookieSessionManager, error) {
+	codec, err := newCookieCodec(key, sessionTTL(idleTimeout), secure)
+	if err != nil {
+		return nil, err
+	}
+	return &CookieSessionManager{codec: codec}, nil
+}
+
+// Set stores session data in the session cookie of the response
+func (m *CookieSessionManager) Set(ctx context.Context, sessionID string, data SessionData) error {
+	return setCookie(ctx, m.codec, SessionCookie, sessionID, data)
+}
+
+// Get retrieves session data from the session cookie of the request, and renews the cookie
+func (m *CookieSessionManager) Get(ctx context.Context, sessionID string) (*SessionData, error) {
+	data, ok, err := getCookie[SessionData](ctx, m.codec, SessionCookie, sessionID)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	if err := setCookie(ctx, m.codec, SessionCookie, sessionID, data); err != nil {
+		return nil, err
+	}
+	return &data, nil
+}
+
+// Delete removes the session cookie
+func (m *CookieSessionManager) Delete(ctx context.Context, _ string) error {
+	return deleteCookie(ctx, m.codec, SessionCookie)
+}
+
+// CookieAuthManager keeps the login state in an encrypted cookie. Only the latest login of a browser is pending,
+// a login started in another tab replaces it.
+type CookieAuthManager struct {
+	codec *cookieCodec
+}
+
+func NewAuthCookieManager(key []byte, secure bool) (*CookieAuthManager, error) {
+	codec, err := newCookieCodec(key, DefaultStateTTL, secure)
+	if err != nil {
+		return nil, err
+	}
+	return &CookieAuthManager{codec: codec}, nil
+}
+
+func (m *CookieAuthManager) SetState(ctx context.Context, state string, data AuthData) error {
+	return setCookie(ctx, m.codec, StateCookie, state, data)
+}
+
+func (m *CookieAuthManager) GetState(ctx context.Context, state string) (*AuthData, error) {
+	stateData, ok, err := getCookie[AuthData](ctx, m.codec, StateCookie, state)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, ErrStateNotFound
+	}
+	return &stateData, nil
+}
+
+func (m *CookieAuthManager) DeleteState(ctx context.Context, _ string) error {
+	return deleteCookie(ctx, m.codec, StateCookie)
+}
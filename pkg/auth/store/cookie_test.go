@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cookie store", func() {
+	var (
+		key      []byte
+		sessions *CookieSessionManager
+		jar      *cookieJar
+	)
+	BeforeEach(func() {
+		key = []byte("0123456789abcdef")
+		var err error
+		sessions, err = NewSessionCookieManager(key, 0, true)
+		Ω(err).ShouldNot(HaveOccurred())
+		jar = &cookieJar{cookies: map[string]*http.Cookie{}}
+		Ω(sessions.Set(jar.ctx(), "id", SessionData{AccessToken: "token"})).Should(Succeed())
+	})
+
+	It("encrypts the session", func() {
+		jar.ctx()
+		cookie := jar.cookies[SessionCookie]
+		Ω(cookie).ShouldNot(BeNil())
+		Ω(cookie.HttpOnly).Should(BeTrue())
+		Ω(cookie.MaxAge).Should(Equal(int(DefaultSessionTTL.Seconds())))
+		Ω(cookie.Value).ShouldNot(ContainSubstring("token"))
+	})
+
+	It("marks the cookies as secure", func() {
+		jar.ctx()
+		Ω(jar.cookies[SessionCookie].Secure).Should(BeTrue())
+
+		insecure, err := NewSessionCookieManager(key, 0, false)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(insecure.Set(jar.ctx(), "id", SessionData{AccessToken: "token"})).Should(Succeed())
+		jar.ctx()
+		Ω(jar.cookies[SessionCookie].Secure).Should(BeFalse())
+	})
+
+	It("splits large sessions into cookies the browsers accept", func() {
+		data := SessionData{AccessToken: fakeToken(1500), IDToken: fakeToken(1200), RefreshToken: fakeToken(1200)}
+		Ω(sessions.Set(jar.ctx(), "id", data)).Should(Succeed())
+		jar.ctx()
+		Ω(jar.cookies).Should(HaveKey(SessionCookie + "_1"))
+		for _, cookie := range jar.cookies {
+			Ω(len(cookie.String())).Should(BeNumerically("<=", maxCookieSize))
+		}
+
+		Ω(sessions.Set(jar.ctx(), "id", SessionData{AccessToken: "token"})).Should(Succeed())
+		jar.ctx()
+		Ω(jar.cookies).ShouldNot(HaveKey(SessionCookie + "_1"))
+
+		Ω(sessions.Set(jar.ctx(), "id", data)).Should(Succeed())
+		Ω(sessions.Delete(jar.ctx(), "id")).Should(Succeed())
+		jar.ctx()
+		Ω(jar.cookies).Should(BeEmpty())
+	})
+
+	It("rejects sessions that are too large for the cookies", func() {
+		data := SessionData{AccessToken: fakeToken(20000)}
+		Ω(sessions.Set(jar.ctx(), "id", data)).Should(MatchError(errCookieTooLarge))
+	})
+
+	It("rejects a tampered cookie", func() {
+		jar.ctx()
+		value := []byte(jar.cookies[SessionCookie].Value)
+		value[len(value)/2] ^= 1
+		jar.cookies[SessionCookie].Value = string(value)
+		_, err := sessions.Get(jar.ctx(), "id")
+		Ω(err).Should(MatchError(ErrSessionNotFound))
+	})
+
+	It("rejects a cookie encrypted with another key", func() {
+		other, err := NewSessionCookieManager([]byte("fedcba9876543210"), 0, true)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, err = other.Get(jar.ctx(), "id")
+		Ω(err).Should(MatchError(ErrSessionNotFound))
+	})
+
+	It("rejects the session cookie as state cookie", func() {
+		states, err := NewAuthCookieManager(key, true)
+		Ω(err).ShouldNot(HaveOccurred())
+		jar.ctx()
+		jar.cookies[StateCookie] = &http.Cookie{Name: StateCookie, Value: jar.cookies[SessionCookie].Value}
+		_, err = states.GetState(jar.ctx(), "id")
+		Ω(err).Should(MatchError(ErrStateNotFound))
+	})
+
+	It("rejects an expired cookie that is still sent", func() {
+		sessions.codec.now = func() time.Time { return time.Now().Add(DefaultSessionTTL) }
+		_, err := sessions.Get(jar.ctx(), "id")
+		Ω(err).Should(MatchError(ErrSessionNotFound))
+	})
+
+	It("requires the gin context", func() {
+		Ω(sessions.Set(context.Background(), "id", SessionData{})).Should(MatchError(errNoGinContext))
+	})
+
+	It("requires a valid key", func() {
+		_, err := NewSessionCookieManager([]byte("short"), 0, true)
+		Ω(err).Should(MatchError(ContainSubstring("invalid encryption key")))
+	})
+})
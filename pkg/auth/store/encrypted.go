@@ -0,0 +1,55 @@
+package store
+
+import (
+	"context"
+	"log"
+)
+
+// refreshTokenField is the name the encrypted refresh tokens are bound to.
+const refreshTokenField = "refresh_token"
+
+// EncryptedSessionStore encrypts the refresh tokens of the sessions with AES-GCM before they are stored,
+// so they are not readable in the backing store.
+type EncryptedSessionStore struct {
+	SessionStore
+	codec *aeadCodec
+}
+
+// NewEncryptedSessionStore wraps the session store. The key must be 16, 24 or 32 bytes long
+// and the same for all replicas.
+func NewEncryptedSessionStore(sessions SessionStore, key []byte) (*EncryptedSessionStore, error) {
+	codec, err := newAEADCodec(key, 0)
+	if err != nil {
+		return nil, err
+	}
+	return &EncryptedSessionStore{SessionStore: sessions, codec: codec}, nil
+}
+
+// Set stores the session data with the encrypted refresh token
+func (e *EncryptedSessionStore) Set(ctx context.Context, sessionID string, data SessionData) error {
+	if data.RefreshToken != "" {
+		encrypted, err := e.codec.encode(refreshTokenField, []byte(data.RefreshToken))
+		if err != nil {
+			return err
+		}
+		data.RefreshToken = encrypted
+	}
+	return e.SessionStore.Set(ctx, sessionID, data)
+}
+
+// Get retrieves the session data with the decrypted refresh token.
+// A refresh token that can't be decrypted is dropped, the session is valid until the access token expires.
+func (e *EncryptedSessionStore) Get(ctx context.Context, sessionID string) (*SessionData, error) {
+	data, err := e.SessionStore.Get(ctx, sessionID)
+	if err != nil || data.RefreshToken == "" {
+		return data, err
+	}
+	plain, err := e.codec.decode(refreshTokenField, data.RefreshToken)
+	if err != nil {
+		log.Printf("Dropping the refresh token of a session, it can't be decrypted: %v", err)
+		data.RefreshToken = ""
+	} else {
+		data.RefreshToken = string(plain)
+	}
+	return data, nil
+}
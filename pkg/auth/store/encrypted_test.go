@@ -0,0 +1,50 @@
+package store
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Encrypted session store", func() {
+	var (
+		ctx       context.Context
+		inner     *MemorySessionManager
+		encrypted *EncryptedSessionStore
+	)
+	BeforeEach(func() {
+		ctx = context.Background()
+		inner = NewSessionMemoryManager(0)
+		var err error
+		encrypted, err = NewEncryptedSessionStore(inner, []byte("0123456789abcdef"))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(encrypted.Set(ctx, "id", SessionData{AccessToken: "access", RefreshToken: "refresh"})).Should(Succeed())
+	})
+
+	It("stores the refresh token encrypted", func() {
+		stored, err := inner.Get(ctx, "id")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(stored.AccessToken).Should(Equal("access"))
+		Ω(stored.RefreshToken).ShouldNot(BeEmpty())
+		Ω(stored.RefreshToken).ShouldNot(ContainSubstring("refresh"))
+
+		data, err := encrypted.Get(ctx, "id")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(data.RefreshToken).Should(Equal("refresh"))
+	})
+
+	It("drops a refresh token encrypted with another key", func() {
+		other, err := NewEncryptedSessionStore(inner, []byte("fedcba9876543210"))
+		Ω(err).ShouldNot(HaveOccurred())
+		data, err := other.Get(ctx, "id")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(data.AccessToken).Should(Equal("access"))
+		Ω(data.RefreshToken).Should(BeEmpty())
+	})
+
+	It("passes the errors of the store", func() {
+		_, err := encrypted.Get(ctx, "unknown")
+		Ω(err).Should(MatchError(ErrSessionNotFound))
+	})
+})
@@ -0,0 +1,132 @@
+package store
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// memoryCache keeps values in memory until their ttl expired.
+// Expired entries are ignored on read, and evicted at most once per ttl on write.
+type memoryCache[T any] struct {
+	mu        sync.Mutex
+	ttl       time.Duration
+	now       func() time.Time
+	entries   map[string]memoryEntry[T]
+	lastSweep time.Time
+}
+
+type memoryEntry[T any] struct {
+	value   T
+	expires time.Time
+}
+
+func newMemoryCache[T any](ttl time.Duration) *memoryCache[T] {
+	return &memoryCache[T]{
+		ttl:     ttl,
+		now:     time.Now,
+		entries: make(map[string]memoryEntry[T]),
+	}
+}
+
+func (m *memoryCache[T]) set(key string, value T) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	if now.Sub(m.lastSweep) >= m.ttl {
+		for k, e := range m.entries {
+			if !now.Before(e.expires) {
+				delete(m.entries, k)
+			}
+		}
+		m.lastSweep = now
+	}
+	m.entries[key] = memoryEntry[T]{value: value, expires: now.Add(m.ttl)}
+}
+
+// get returns the value of the key, renew extends its expiry by the ttl.
+func (m *memoryCache[T]) get(key string, renew bool) (T, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	now := m.now()
+	e, ok := m.entries[key]
+	if !ok || !now.Before(e.expires) {
+		var zero T
+		return zero, false
+	}
+	if renew {
+		e.expires = now.Add(m.ttl)
+		m.entries[key] = e
+	}
+	return e.value, true
+}
+
+func (m *memoryCache[T]) delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.entries, key)
+}
+
+func (m *memoryCache[T]) len() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return len(m.entries)
+}
+
+// MemorySessionManager keeps the sessions in memory, it can only be used with a single replica.
+type MemorySessionManager struct {
+	sessions *memoryCache[SessionData]
+}
+
+// NewSessionMemoryManager creates the in-memory session store, sessions expire after the idle timeout.
+func NewSessionMemoryManager(idleTimeout time.Duration) *MemorySessionManager {
+	return &MemorySessionManager{sessions: newMemoryCache[SessionData](sessionTTL(idleTimeout))}
+}
+
+// Set stores session data in memory
+func (m *MemorySessionManager) Set(_ context.Context, sessionID string, data SessionData) error {
+	m.sessions.set(sessionID, data)
+	return nil
+}
+
+// Get retrieves session data from memory
+func (m *MemorySessionManager) Get(_ context.Context, sessionID string) (*SessionData, error) {
+	data, ok := m.sessions.get(sessionID, true)
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	return &data, nil
+}
+
+// Delete removes a session from memory
+func (m *MemorySessionManager) Delete(_ context.Context, sessionID string) error {
+	m.sessions.delete(sessionID)
+	return nil
+}
+
+// MemoryAuthManager keeps the login states in memory, it can only be used with a single replica.
+type MemoryAuthManager struct {
+	states *memoryCache[AuthData]
+}
+
+func NewAuthMemoryManager() *MemoryAuthManager {
+	return &MemoryAuthManager{states: newMemoryCache[AuthData](DefaultStateTTL)}
+}
+
+func (m *MemoryAuthManager) SetState(_ context.Context, state string, data AuthData) error {
+	m.states.set(state, data)
+	return nil
+}
+
+func (m *MemoryAuthManager) GetState(_ context.Context, state string) (*AuthData, error) {
+	stateData, ok := m.states.get(state, false)
+	if !ok {
+		return nil, ErrStateNotFound
+	}
+	return &stateData, nil
+}
+
+func (m *MemoryAuthManager) DeleteState(_ context.Context, state string) error {
+	m.states.delete(state)
+	return nil
+}
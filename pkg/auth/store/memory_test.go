@@ -0,0 +1,29 @@
+package store
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Memory store", func() {
+	It("evicts the expired entries", func() {
+		clock := &fakeClock{t: time.Now()}
+		cache := newMemoryCache[string](time.Minute)
+		cache.now = clock.now
+
+		cache.set("a", "a")
+		clock.advance(30 * time.Second)
+		cache.set("b", "b")
+		Ω(cache.len()).Should(Equal(2))
+
+		clock.advance(30 * time.Second)
+		cache.set("c", "c")
+		Ω(cache.len()).Should(Equal(2))
+		_, ok := cache.get("a", false)
+		Ω(ok).Should(BeFalse())
+		_, ok = cache.get("b", false)
+		Ω(ok).Should(BeTrue())
+	})
+})
@@ -9,49 +9,12 @@ import (
 	"github.com/redis/go-redis/v9"
 )
 
-// SessionData represents the data we'll store for each session
-type SessionData struct {
-	AccessToken string    `json:"access_token"`
-	UserInfo    UserInfo  `json:"user_info"`
-	CreatedAt   time.Time `json:"created_at"`
-}
-
-// UserInfo contains the essential user information we want to cache
-type UserInfo struct {
-	Username string   `json:"username"`
-	Email    string   `json:"email"`
-	Groups   []string `json:"groups"`
-	// Add other user fields you need
-}
-
-// SessionStore defines the contract for session management
-type SessionStore interface {
-	Set(ctx context.Context, sessionID string, data SessionData) error
-	Get(ctx context.Context, sessionID string) (*SessionData, error)
-	Delete(ctx context.Context, sessionID string) error
-}
-
 type RedisSessionManager struct {
 	client      *redis.Client
 	PrefixState string
 	defaultTTL  time.Duration
 }
 
-type AuthData struct {
-}
-
-type AuthStore interface {
-	SetState(ctx context.Context, state string) error
-	GetState(
-		ctx context.Context,
-		state string,
-	) (string, error)
-	DeleteState(
-		ctx context.Context,
-		state string,
-	) error
-}
-
 type RedisAuthManager struct {
 	client      *redis.Client
 	PrefixState string
@@ -62,15 +25,16 @@ func NewAuthRedisManager(rds *redis.Client) *RedisAuthManager {
 	return &RedisAuthManager{
 		client:      rds,
 		PrefixState: "stateauth",
-		defaultTTL:  2 * time.Minute,
+		defaultTTL:  DefaultStateTTL,
 	}
 }
 
-func NewSessionRedisManager(rds *redis.Client) *RedisSessionManager {
+// NewSessionRedisManager creates the Redis session store, sessions expire after the idle timeout.
+func NewSessionRedisManager(rds *redis.Client, idleTimeout time.Duration) *RedisSessionManager {
 	return &RedisSessionManager{
 		client:      rds,
 		PrefixState: "session",
-		defaultTTL:  5 * time.Minute,
+		defaultTTL:  sessionTTL(idleTimeout),
 	}
 }
 func (r *RedisSessionManager) buildKeyState(session string) string {
@@ -92,13 +56,13 @@ func (r *RedisSessionManager) Set(ctx context.Context, sessionID string, data Se
 	return r.client.Set(ctx, key, jsonData, r.defaultTTL).Err()
 }
 
-// Get retrieves session data from Redis
+// Get retrieves session data from Redis and renews its TTL
 func (r *RedisSessionManager) Get(ctx context.Context, sessionID string) (*SessionData, error) {
 	key := r.buildKeyState(sessionID)
-	data, err := r.client.Get(ctx, key).Result()
+	data, err := r.client.GetEx(ctx, key, r.defaultTTL).Result()
 
 	if err == redis.Nil {
-		return nil, fmt.Errorf("session not found")
+		return nil, ErrSessionNotFound
 	}
 	if err != nil {
 		return nil, fmt.Errorf("failed to get session: %w", err)
@@ -118,11 +82,16 @@ func (r *RedisSessionManager) Delete(ctx context.Context, sessionID string) erro
 	return r.client.Del(ctx, key).Err()
 }
 
-func (r *RedisAuthManager) SetState(ctx context.Context, state string) error {
+func (r *RedisAuthManager) SetState(ctx context.Context, state string, data AuthData) error {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to marshal state data: %w", err)
+	}
+
 	key := r.buildKeyState(state)
 	expiration := r.defaultTTL
 
-	err := r.client.Set(ctx, key, state, expiration).Err()
+	err = r.client.Set(ctx, key, jsonData, expiration).Err()
 	if err != nil {
 		return fmt.Errorf("failed to set session in Redis: %w", err)
 	}
@@ -132,13 +101,21 @@ func (r *RedisAuthManager) SetState(ctx context.Context, state string) error {
 func (r *RedisAuthManager) GetState(
 	ctx context.Context,
 	state string,
-) (string, error) {
+) (*AuthData, error) {
 	key := r.buildKeyState(state)
-	stateData, err := r.client.Get(ctx, key).Result()
+	data, err := r.client.Get(ctx, key).Result()
+	if err == redis.Nil {
+		return nil, ErrStateNotFound
+	}
 	if err != nil {
-		return "", fmt.Errorf("failed to get state data from Redis: %w", err)
+		return nil, fmt.Errorf("failed to get state data from Redis: %w", err)
+	}
+
+	var stateData AuthData
+	if err := json.Unmarshal([]byte(data), &stateData); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal state data: %w", err)
 	}
-	return stateData, nil
+	return &stateData, nil
 }
 
 func (r *RedisAuthManager) DeleteState(
@@ -0,0 +1,79 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultSessionTTL is the idle timeout of the sessions, each access renews it.
+	DefaultSessionTTL = time.Hour
+	// DefaultStateTTL is the time a login has to complete.
+	DefaultStateTTL = 2 * time.Minute
+)
+
+var (
+	// ErrSessionNotFound is returned if the session does not exist or is expired.
+	ErrSessionNotFound = errors.New("session not found")
+	// ErrStateNotFound is returned if the login state does not exist or is expired.
+	ErrStateNotFound = errors.New("state not found")
+)
+
+// SessionData represents the data we'll store for each session
+type SessionData struct {
+	AccessToken string `json:"access_token"`
+	// RefreshToken is encrypted by the EncryptedSessionStore before it is stored
+	RefreshToken string `json:"refresh_token,omitempty"`
+	// IDToken is sent as hint on logout
+	IDToken string `json:"id_token,omitempty"`
+	// Expiry is the expiry of the access token
+	Expiry    time.Time `json:"expiry,omitempty"`
+	UserInfo  UserInfo  `json:"user_info"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// UserInfo contains the essential user information we want to cache
+type UserInfo struct {
+	Username string   `json:"username"`
+	Email    string   `json:"email"`
+	Groups   []string `json:"groups"`
+	// Add other user fields you need
+}
+
+// SessionStore defines the contract for session management.
+// Sessions expire if they are not accessed within the TTL, Get renews the TTL.
+type SessionStore interface {
+	Set(ctx context.Context, sessionID string, data SessionData) error
+	Get(ctx context.Context, sessionID string) (*SessionData, error)
+	Delete(ctx context.Context, sessionID string) error
+}
+
+// AuthData is kept for each pending login until the callback
+type AuthData struct {
+	// CodeVerifier is the PKCE verifier of the code challenge sent with the login
+	CodeVerifier string `json:"code_verifier"`
+	// Nonce has to match the nonce claim of the ID token
+	Nonce string `json:"nonce"`
+}
+
+// AuthStore keeps the state of the pending logins
+type AuthStore interface {
+	SetState(ctx context.Context, state string, data AuthData) error
+	GetState(
+		ctx context.Context,
+		state string,
+	) (*AuthData, error)
+	DeleteState(
+		ctx context.Context,
+		state string,
+	) error
+}
+
+// sessionTTL returns the idle timeout, or the default if none is configured.
+func sessionTTL(idleTimeout time.Duration) time.Duration {
+	if idleTimeout <= 0 {
+		return DefaultSessionTTL
+	}
+	return idleTimeout
+}
@@ -0,0 +1,105 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+// AuthMode selects how the users are authenticated.
+type AuthMode string
+
+const (
+	// AuthModeNone disables the authentication, all endpoints are open.
+	AuthModeNone AuthMode = "none"
+	// AuthModeOIDC logs in the users with an OpenID Connect provider.
+	AuthModeOIDC AuthMode = "oidc"
+	// AuthModeHeader trusts the user headers set by an authenticating reverse proxy.
+	AuthModeHeader AuthMode = "header"
+)
+
+const (
+	// DefaultUserHeader is the header of the username set by the reverse proxy.
+	DefaultUserHeader = "X-Forwarded-User"
+	// DefaultGroupsHeader is the header of the comma separated groups set by the reverse proxy.
+	DefaultGroupsHeader = "X-Forwarded-Groups"
+	// DefaultEmailHeader is the header of the email set by the reverse proxy.
+	DefaultEmailHeader = "X-Forwarded-Email"
+)
+
+// Auth configures the authentication of the users.
+type Auth struct {
+	Mode   AuthMode   `yaml:"mode"`
+	Header HeaderAuth `yaml:"header"`
+}
+
+// HeaderAuth configures the headers of the authenticating reverse proxy, e.g. oauth2-proxy or Pomerium.
+// The headers are only trusted from requests of the trusted proxies.
+type HeaderAuth struct {
+	UserHeader     string   `yaml:"userHeader"`
+	GroupsHeader   string   `yaml:"groupsHeader"`
+	EmailHeader    string   `yaml:"emailHeader"`
+	TrustedProxies []string `yaml:"trustedProxies"`
+}
+
+// ErrNoTrustedProxies is returned for the header auth mode without trusted proxies.
+var ErrNoTrustedProxies = errors.New(
+	"the header auth mode requires trusted proxies, as anybody else could set the user headers",
+)
+
+// Proxies returns the networks of the trusted proxies. Single IP addresses are accepted as well.
+// Without trusted proxies, ErrNoTrustedProxies is returned.
+func (h HeaderAuth) Proxies() ([]*net.IPNet, error) {
+	if len(h.TrustedProxies) == 0 {
+		return nil, ErrNoTrustedProxies
+	}
+	var nets []*net.IPNet
+	for _, p := range h.TrustedProxies {
+		if !strings.Contains(p, "/") {
+			ip := net.ParseIP(p)
+			if ip == nil {
+				return nil, fmt.Errorf("invalid trusted proxy '%s'", p)
+			}
+			bits := 8 * net.IPv6len
+			if ip.To4() != nil {
+				ip, bits = ip.To4(), 8*net.IPv4len
+			}
+			nets = append(nets, &net.IPNet{IP: ip, Mask: net.CIDRMask(bits, bits)})
+			continue
+		}
+		_, n, err := net.ParseCIDR(p)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy '%s': %w", p, err)
+		}
+		nets = append(nets, n)
+	}
+	return nets, nil
+}
+
+func (a *Auth) validate() error {
+	switch a.Mode {
+	case "":
+		a.Mode = AuthModeNone
+	case AuthModeNone, AuthModeOIDC:
+	case AuthModeHeader:
+		return a.Header.validate()
+	default:
+		return fmt.Errorf("invalid auth mode '%s'", a.Mode)
+	}
+	return nil
+}
+
+func (h *HeaderAuth) validate() error {
+	if h.UserHeader == "" {
+		h.UserHeader = DefaultUserHeader
+	}
+	if h.GroupsHeader == "" {
+		h.GroupsHeader = DefaultGroupsHeader
+	}
+	if h.EmailHeader == "" {
+		h.EmailHeader = DefaultEmailHeader
+	}
+	_, err := h.Proxies()
+	return err
+}
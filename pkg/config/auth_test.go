@@ -0,0 +1,50 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Auth", func() {
+	Context("header mode", func() {
+		It("should set the default headers", func() {
+			a := Auth{Mode: AuthModeHeader, Header: HeaderAuth{TrustedProxies: []string{"10.0.0.0/8"}}}
+			Ω(a.validate()).Should(Succeed())
+			Ω(a.Header.UserHeader).Should(Equal("X-Forwarded-User"))
+			Ω(a.Header.GroupsHeader).Should(Equal("X-Forwarded-Groups"))
+			Ω(a.Header.EmailHeader).Should(Equal("X-Forwarded-Email"))
+		})
+		It("should keep the configured headers", func() {
+			a := Auth{Mode: AuthModeHeader, Header: HeaderAuth{
+				UserHeader:     "X-Pomerium-Claim-User",
+				TrustedProxies: []string{"10.0.0.0/8"},
+			}}
+			Ω(a.validate()).Should(Succeed())
+			Ω(a.Header.UserHeader).Should(Equal("X-Pomerium-Claim-User"))
+		})
+		It("should require trusted proxies", func() {
+			a := Auth{Mode: AuthModeHeader}
+			Ω(a.validate()).Should(MatchError(ContainSubstring("the header auth mode requires trusted proxies")))
+		})
+		It("should fail for an invalid trusted proxy", func() {
+			a := Auth{Mode: AuthModeHeader, Header: HeaderAuth{TrustedProxies: []string{"10.0.0.0/33"}}}
+			Ω(a.validate()).Should(MatchError(ContainSubstring("invalid trusted proxy '10.0.0.0/33'")))
+			a.Header.TrustedProxies = []string{"proxy.local"}
+			Ω(a.validate()).Should(MatchError("invalid trusted proxy 'proxy.local'"))
+		})
+		It("should not require trusted proxies in other modes", func() {
+			a := Auth{Mode: AuthModeOIDC}
+			Ω(a.validate()).Should(Succeed())
+		})
+	})
+	Context("Proxies", func() {
+		It("should accept networks and single addresses", func() {
+			nets, err := HeaderAuth{TrustedProxies: []string{"10.0.0.0/8", "192.168.1.10", "fd00::1"}}.Proxies()
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(nets).Should(HaveLen(3))
+			Ω(nets[0].String()).Should(Equal("10.0.0.0/8"))
+			Ω(nets[1].String()).Should(Equal("192.168.1.10/32"))
+			Ω(nets[2].String()).Should(Equal("fd00::1/128"))
+		})
+	})
+})
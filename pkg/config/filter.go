@@ -2,6 +2,25 @@ package config
 
 import "k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+// ServerSideFields are the fields set by the api server or the controller,
+// they are removed from resources that are to be stored in Git.
+var ServerSideFields = &FieldFilter{
+	Skip: [][]string{
+		{"status"},
+		{"metadata", "managedFields"},
+		{"metadata", "resourceVersion"},
+		{"metadata", "uid"},
+		{"metadata", "generation"},
+		{"metadata", "selfLink"},
+		{"metadata", "creationTimestamp"},
+		{"metadata", "deletionTimestamp"},
+		{"metadata", "deletionGracePeriodSeconds"},
+		{"metadata", "ownerReferences"},
+		{"metadata", "finalizers"},
+		{"metadata", "annotations", "kubectl.kubernetes.io/last-applied-configuration"},
+	},
+}
+
 type FieldFilter struct {
 	Skip      [][]string `yaml:"skip"`
 	SkipIfNil [][]string `yaml:"skipIfNil"`
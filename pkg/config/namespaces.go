@@ -0,0 +1,135 @@
+package config
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// NamespaceFilter matches namespaces against include and exclude patterns.
+// A pattern is a glob (e.g. team-*) or a regular expression enclosed in slashes (e.g. /team-(a|b)/),
+// which has to match the whole namespace name.
+type NamespaceFilter struct {
+	include []namespacePattern
+	exclude []namespacePattern
+}
+
+type namespacePattern struct {
+	glob  string
+	regex *regexp.Regexp
+}
+
+// NewNamespaceFilter compiles the include and exclude patterns. Empty patterns are ignored.
+func NewNamespaceFilter(include, exclude []string) (*NamespaceFilter, error) {
+	f := &NamespaceFilter{}
+	var err error
+	if f.include, err = compileNamespacePatterns(include); err != nil {
+		return nil, err
+	}
+	if f.exclude, err = compileNamespacePatterns(exclude); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func compileNamespacePatterns(patterns []string) ([]namespacePattern, error) {
+	var compiled []namespacePattern
+	for _, p := range patterns {
+		p = strings.TrimSpace(p)
+		if p == "" {
+			continue
+		}
+		if len(p) > 2 && strings.HasPrefix(p, "/") && strings.HasSuffix(p, "/") {
+			re, err := regexp.Compile("^(?:" + p[1:len(p)-1] + ")$")
+			if err != nil {
+				return nil, fmt.Errorf("invalid namespace pattern '%s': %w", p, err)
+			}
+			compiled = append(compiled, namespacePattern{regex: re})
+			continue
+		}
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid namespace pattern '%s': %w", p, err)
+		}
+		compiled = append(compiled, namespacePattern{glob: p})
+	}
+	return compiled, nil
+}
+
+func (p namespacePattern) matches(ns string) bool {
+	if p.regex != nil {
+		return p.regex.MatchString(ns)
+	}
+	ok, _ := path.Match(p.glob, ns)
+	return ok
+}
+
+func (p namespacePattern) literal() bool {
+	return p.regex == nil && !strings.ContainsAny(p.glob, `*?[\`)
+}
+
+// Matches returns true if the namespace is included and not excluded.
+// Without include patterns, all namespaces are included.
+func (f *NamespaceFilter) Matches(ns string) bool {
+	if f == nil {
+		return true
+	}
+	included := len(f.include) == 0
+	for _, p := range f.include {
+		if p.matches(ns) {
+			included = true
+			break
+		}
+	}
+	if !included {
+		return false
+	}
+	for _, p := range f.exclude {
+		if p.matches(ns) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty returns true if all namespaces are matched.
+func (f *NamespaceFilter) Empty() bool {
+	return f == nil || (len(f.include) == 0 && len(f.exclude) == 0)
+}
+
+// MatchesAll returns true if the filter matches every namespace, which is required for cluster-wide secrets.
+// That is the case without patterns, or with the include pattern '*' and no exclude patterns.
+func (f *NamespaceFilter) MatchesAll() bool {
+	if f.Empty() {
+		return true
+	}
+	if len(f.exclude) > 0 {
+		return false
+	}
+	for _, p := range f.include {
+		if p.regex == nil && p.glob == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// Literals returns the sorted included namespaces if all include patterns are plain namespace names,
+// so the matching namespaces are known without listing all namespaces.
+func (f *NamespaceFilter) Literals() ([]string, bool) {
+	if f == nil || len(f.include) == 0 {
+		return nil, false
+	}
+	var namespaces []string
+	for _, p := range f.include {
+		if !p.literal() {
+			return nil, false
+		}
+		if f.Matches(p.glob) {
+			namespaces = append(namespaces, p.glob)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, true
+}
@@ -0,0 +1,67 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("NamespaceFilter", func() {
+	It("should match all namespaces without patterns", func() {
+		f, err := NewNamespaceFilter(nil, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(f.Empty()).Should(BeTrue())
+		Ω(f.Matches("foo")).Should(BeTrue())
+	})
+
+	DescribeTable("should match namespaces",
+		func(include, exclude []string, ns string, expected bool) {
+			f, err := NewNamespaceFilter(include, exclude)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(f.Matches(ns)).Should(Equal(expected))
+		},
+		Entry("literal", []string{"foo"}, nil, "foo", true),
+		Entry("other literal", []string{"foo"}, nil, "bar", false),
+		Entry("glob", []string{"team-*"}, nil, "team-a", true),
+		Entry("glob not matching", []string{"team-*"}, nil, "teams", false),
+		Entry("regex", []string{"/team-(a|b)/"}, nil, "team-b", true),
+		Entry("regex matches the whole name", []string{"/team-(a|b)/"}, nil, "team-bc", false),
+		Entry("excluded glob", []string{"team-*"}, []string{"*-prod"}, "team-prod", false),
+		Entry("excluded regex", nil, []string{"/kube-.*/"}, "kube-system", false),
+		Entry("not excluded", nil, []string{"/kube-.*/"}, "default", true),
+	)
+
+	DescribeTable("should match all namespaces",
+		func(include, exclude []string, expected bool) {
+			f, err := NewNamespaceFilter(include, exclude)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(f.MatchesAll()).Should(Equal(expected))
+		},
+		Entry("without patterns", nil, nil, true),
+		Entry("with the wildcard", []string{"foo", "*"}, nil, true),
+		Entry("with a glob", []string{"team-*"}, nil, false),
+		Entry("with an exclude", nil, []string{"kube-*"}, false),
+		Entry("with the wildcard and an exclude", []string{"*"}, []string{"kube-*"}, false),
+	)
+
+	It("should return the literals", func() {
+		f, err := NewNamespaceFilter([]string{"foo", "bar", "baz"}, []string{"baz"})
+		Ω(err).ShouldNot(HaveOccurred())
+		literals, ok := f.Literals()
+		Ω(ok).Should(BeTrue())
+		Ω(literals).Should(Equal([]string{"bar", "foo"}))
+	})
+
+	It("should not return literals for patterns", func() {
+		f, err := NewNamespaceFilter([]string{"foo", "team-*"}, nil)
+		Ω(err).ShouldNot(HaveOccurred())
+		_, ok := f.Literals()
+		Ω(ok).Should(BeFalse())
+	})
+
+	It("should fail for invalid patterns", func() {
+		_, err := NewNamespaceFilter([]string{"/team-(/"}, nil)
+		Ω(err).Should(MatchError(ContainSubstring("invalid namespace pattern '/team-(/'")))
+		_, err = NewNamespaceFilter(nil, []string{"team-["})
+		Ω(err).Should(MatchError(ContainSubstring("invalid namespace pattern 'team-['")))
+	})
+})
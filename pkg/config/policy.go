@@ -0,0 +1,104 @@
+package config
+
+import (
+	"fmt"
+	"slices"
+)
+
+// Operation is an operation on secrets that is authorized by the policy.
+type Operation string
+
+const (
+	// OperationList lists and watches the sealed secrets.
+	OperationList Operation = "list"
+	// OperationRead reads a secret or sealed secret.
+	OperationRead Operation = "read"
+	// OperationSeal seals secrets and values.
+	OperationSeal Operation = "seal"
+	// OperationValidate validates sealed secrets.
+	OperationValidate Operation = "validate"
+	// OperationUnseal unseals sealed secrets.
+	OperationUnseal Operation = "unseal"
+)
+
+// Operations are all operations that can be granted.
+var Operations = []Operation{OperationList, OperationRead, OperationSeal, OperationValidate, OperationUnseal}
+
+// Authorization maps the groups of the users to the namespaces and operations they are allowed to use.
+// Without rules, all users are allowed to use all namespaces.
+type Authorization struct {
+	Rules []AuthorizationRule `yaml:"rules"`
+}
+
+// AuthorizationRule grants the operations in the namespaces to the members of the groups.
+// The group "*" matches all users. Namespaces are patterns like the included namespaces.
+// Without namespaces or operations, all namespaces or operations are granted.
+type AuthorizationRule struct {
+	Groups     []string    `yaml:"groups"`
+	Namespaces []string    `yaml:"namespaces,omitempty"`
+	Operations []Operation `yaml:"operations,omitempty"`
+}
+
+// Policy decides which operations users are allowed to use in which namespaces.
+type Policy struct {
+	rules []policyRule
+}
+
+type policyRule struct {
+	groups     []string
+	namespaces *NamespaceFilter
+	operations []Operation
+}
+
+// NewPolicy compiles the authorization rules.
+func NewPolicy(a Authorization) (*Policy, error) {
+	p := &Policy{}
+	for i, r := range a.Rules {
+		if len(r.Groups) == 0 {
+			return nil, fmt.Errorf("authorization rule %d has no groups", i)
+		}
+		for _, op := range r.Operations {
+			if !slices.Contains(Operations, op) {
+				return nil, fmt.Errorf("authorization rule %d has an invalid operation '%s'", i, op)
+			}
+		}
+		namespaces, err := NewNamespaceFilter(r.Namespaces, nil)
+		if err != nil {
+			return nil, fmt.Errorf("authorization rule %d: %w", i, err)
+		}
+		p.rules = append(p.rules, policyRule{groups: r.Groups, namespaces: namespaces, operations: r.Operations})
+	}
+	return p, nil
+}
+
+// Enabled returns true if the policy has rules.
+func (p *Policy) Enabled() bool {
+	return p != nil && len(p.rules) > 0
+}
+
+// Allowed returns true if a rule grants the operation in the namespace to one of the groups.
+// The empty namespace stands for all namespaces (cluster-wide secrets), it is only granted by rules
+// matching all namespaces. A disabled policy allows everything.
+func (p *Policy) Allowed(groups []string, op Operation, namespace string) bool {
+	if !p.Enabled() {
+		return true
+	}
+	for _, r := range p.rules {
+		if r.grants(groups, op, namespace) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r policyRule) grants(groups []string, op Operation, namespace string) bool {
+	if len(r.operations) > 0 && !slices.Contains(r.operations, op) {
+		return false
+	}
+	if namespace == "" && !r.namespaces.MatchesAll() || namespace != "" && !r.namespaces.Matches(namespace) {
+		return false
+	}
+	return slices.Contains(r.groups, "*") || slices.ContainsFunc(groups, func(g string) bool {
+		return slices.Contains(r.groups, g)
+	})
+}
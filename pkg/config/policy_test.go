@@ -0,0 +1,49 @@
+package config
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Policy", func() {
+	var policy *Policy
+	BeforeEach(func() {
+		var err error
+		policy, err = NewPolicy(Authorization{Rules: []AuthorizationRule{
+			{Groups: []string{"team-a"}, Namespaces: []string{"team-a-*"}},
+			{Groups: []string{"*"}, Namespaces: []string{"shared"}, Operations: []Operation{OperationList, OperationSeal}},
+			{Groups: []string{"admins"}},
+		}})
+		Ω(err).ShouldNot(HaveOccurred())
+	})
+
+	DescribeTable("should authorize the operations",
+		func(groups []string, op Operation, namespace string, expected bool) {
+			Ω(policy.Allowed(groups, op, namespace)).Should(Equal(expected))
+		},
+		Entry("group namespace", []string{"team-a"}, OperationRead, "team-a-dev", true),
+		Entry("other namespace", []string{"team-a"}, OperationRead, "team-b-dev", false),
+		Entry("all users", []string{"team-b"}, OperationList, "shared", true),
+		Entry("users without groups", nil, OperationSeal, "shared", true),
+		Entry("operation not granted", []string{"team-b"}, OperationRead, "shared", false),
+		Entry("all namespaces and operations", []string{"admins"}, OperationUnseal, "kube-system", true),
+		Entry("cluster-wide", []string{"admins"}, OperationSeal, "", true),
+		Entry("cluster-wide with namespace patterns", []string{"team-a"}, OperationSeal, "", false),
+	)
+
+	It("should allow everything without rules", func() {
+		p, err := NewPolicy(Authorization{})
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(p.Enabled()).Should(BeFalse())
+		Ω(p.Allowed(nil, OperationUnseal, "kube-system")).Should(BeTrue())
+	})
+
+	It("should fail for invalid rules", func() {
+		_, err := NewPolicy(Authorization{Rules: []AuthorizationRule{{}}})
+		Ω(err).Should(MatchError("authorization rule 0 has no groups"))
+		_, err = NewPolicy(Authorization{Rules: []AuthorizationRule{{Groups: []string{"a"}, Operations: []Operation{"delete"}}}})
+		Ω(err).Should(MatchError("authorization rule 0 has an invalid operation 'delete'"))
+		_, err = NewPolicy(Authorization{Rules: []AuthorizationRule{{Groups: []string{"a"}, Namespaces: []string{"/(/"}}}})
+		Ω(err).Should(MatchError(ContainSubstring("authorization rule 0: invalid namespace pattern '/(/'")))
+	})
+})
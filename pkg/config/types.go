@@ -2,13 +2,16 @@ package config
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"log"
 	"os"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+	"k8s.io/apimachinery/pkg/labels"
 )
 
 func Parse() (*Config, error) {
@@ -25,6 +28,10 @@ func parse(f *flags) (*Config, error) {
 		},
 		PrintVersion:       *f.printVersion,
 		DisableLoadSecrets: *f.disableLoadSecrets,
+		EnableCache:        *f.enableCache,
+		Impersonate:        *f.impersonate,
+		AccessReview:       *f.accessReview,
+		Auth:               Auth{Mode: AuthMode(*f.authMode)},
 	}
 
 	if *f.kubesealArgs != "" {
@@ -45,9 +52,33 @@ func parse(f *flags) (*Config, error) {
 	if *f.sealedSecretsServiceNamespace != "" {
 		cfg.SealedSecrets.Namespace = *f.sealedSecretsServiceNamespace
 	}
+	if *f.sealedSecretsCertFile != "" {
+		cfg.SealedSecrets.CertFile = *f.sealedSecretsCertFile
+	}
+
+	certRefreshInterval := *f.sealedSecretsCertRefreshInterval
+	cfg.SealedSecrets.CertRefreshInterval = &certRefreshInterval
 
 	if *f.includeNamespaces != "" {
-		cfg.IncludeNamespaces = strings.Split(*f.includeNamespaces, " ")
+		cfg.IncludeNamespaces = strings.Fields(*f.includeNamespaces)
+	}
+	if *f.excludeNamespaces != "" {
+		cfg.ExcludeNamespaces = strings.Fields(*f.excludeNamespaces)
+	}
+	if *f.namespaceSelector != "" {
+		cfg.NamespaceSelector = *f.namespaceSelector
+	}
+	if *f.authUserHeader != "" {
+		cfg.Auth.Header.UserHeader = *f.authUserHeader
+	}
+	if *f.authGroupsHeader != "" {
+		cfg.Auth.Header.GroupsHeader = *f.authGroupsHeader
+	}
+	if *f.authEmailHeader != "" {
+		cfg.Auth.Header.EmailHeader = *f.authEmailHeader
+	}
+	if *f.trustedProxies != "" {
+		cfg.Auth.Header.TrustedProxies = strings.Fields(*f.trustedProxies)
 	}
 	if *f.initialSecretFile != "" {
 		b, err := os.ReadFile(*f.initialSecretFile)
@@ -79,6 +110,34 @@ func parse(f *flags) (*Config, error) {
 		}
 	}
 
+	if err := setupControllers(cfg); err != nil {
+		return nil, err
+	}
+
+	if _, err := NewNamespaceFilter(cfg.IncludeNamespaces, cfg.ExcludeNamespaces); err != nil {
+		return nil, err
+	}
+	if _, err := labels.Parse(cfg.NamespaceSelector); err != nil {
+		return nil, fmt.Errorf("invalid namespace selector '%s': %w", cfg.NamespaceSelector, err)
+	}
+	if _, err := NewPolicy(cfg.Authorization); err != nil {
+		return nil, err
+	}
+	if err := cfg.Auth.validate(); err != nil {
+		return nil, err
+	}
+	if err := cfg.Unseal.validate(cfg.Auth.Mode); err != nil {
+		return nil, err
+	}
+	if cfg.Impersonate && cfg.EnableCache {
+		return nil, errors.New("the cache can't be used with impersonation, as it would bypass the permissions of the users")
+	}
+
+	if cfg.Offline() {
+		// without cluster access, there are no secrets to be loaded
+		cfg.DisableLoadSecrets = true
+	}
+
 	cfg.Web.Context = sanitizeWebContext(cfg)
 
 	cfg.Ctx = context.Background()
@@ -99,17 +158,121 @@ func sanitizeWebContext(cfg *Config) string {
 	return wc
 }
 
+// setupControllers validates the controller names and defaults the service name, namespace
+// and certificate refresh interval of each controller to the ones of SealedSecrets.
+func setupControllers(cfg *Config) error {
+	names := make(map[string]bool)
+	for i := range cfg.Controllers {
+		c := &cfg.Controllers[i]
+		if c.Name == "" {
+			return fmt.Errorf("controller %d has no name", i)
+		}
+		if names[c.Name] {
+			return fmt.Errorf("controller '%s' is defined more than once", c.Name)
+		}
+		names[c.Name] = true
+		if c.Service == "" {
+			c.Service = cfg.SealedSecrets.Service
+		}
+		if c.Namespace == "" {
+			c.Namespace = cfg.SealedSecrets.Namespace
+		}
+		if c.CertRefreshInterval == nil {
+			c.CertRefreshInterval = cfg.SealedSecrets.CertRefreshInterval
+		}
+	}
+	return nil
+}
+
 type Config struct {
 	Web                Web             `yaml:"web"`
 	FieldFilter        *FieldFilter    `yaml:"fieldFilter,omitempty"`
 	PrintVersion       bool            `yaml:"printVersion"`
 	DisableLoadSecrets bool            `yaml:"disableLoadSecrets"`
+	EnableCache        bool            `yaml:"enableCache"`
+	Impersonate        bool            `yaml:"impersonate"`
+	AccessReview       bool            `yaml:"accessReview"`
 	IncludeNamespaces  []string        `yaml:"includeNamespaces"`
+	ExcludeNamespaces  []string        `yaml:"excludeNamespaces"`
+	NamespaceSelector  string          `yaml:"namespaceSelector"`
 	SealedSecrets      SealedSecrets   `yaml:"sealedSecrets"`
+	Controllers        []Controller    `yaml:"controllers,omitempty"`
+	Unseal             Unseal          `yaml:"unseal"`
+	Authorization      Authorization   `yaml:"authorization"`
+	Auth               Auth            `yaml:"auth"`
 	InitialSecret      string          `yaml:"initialSecret"`
 	Ctx                context.Context `yaml:"-"`
 }
 
+// GetControllers returns the configured sealed secrets controllers.
+// If no controllers are configured, the controller defined in SealedSecrets is returned as "default".
+func (c *Config) GetControllers() []Controller {
+	if len(c.Controllers) > 0 {
+		return c.Controllers
+	}
+	return []Controller{{Name: DefaultController, SealedSecrets: c.SealedSecrets}}
+}
+
+// NamespaceFilter returns the filter of the included and excluded namespaces.
+// Invalid patterns are rejected when parsing the config.
+func (c *Config) NamespaceFilter() *NamespaceFilter {
+	f, _ := NewNamespaceFilter(c.IncludeNamespaces, c.ExcludeNamespaces)
+	return f
+}
+
+// Policy returns the authorization policy. Invalid rules are rejected when parsing the config.
+func (c *Config) Policy() *Policy {
+	p, _ := NewPolicy(c.Authorization)
+	return p
+}
+
+// Offline returns true if all controllers are used without cluster access.
+func (c *Config) Offline() bool {
+	for _, ctrl := range c.GetControllers() {
+		if !ctrl.Offline() {
+			return false
+		}
+	}
+	return true
+}
+
+// Controller returns the controller with the given name. An empty name returns the first controller.
+func (c *Config) Controller(name string) (Controller, bool) {
+	controllers := c.GetControllers()
+	if name == "" {
+		return controllers[0], true
+	}
+	for _, ctrl := range controllers {
+		if ctrl.Name == name {
+			return ctrl, true
+		}
+	}
+	return Controller{}, false
+}
+
+// Unseal configures the break-glass unsealing of sealed secrets with the private keys of the controller.
+type Unseal struct {
+	Enabled bool `yaml:"enabled"`
+	// KeyFile contains the private keys (PEM or the exported sealing key secrets).
+	KeyFile string `yaml:"keyFile"`
+	// AdminGroups are the groups of the users allowed to unseal.
+	AdminGroups []string `yaml:"adminGroups"`
+}
+
+// validate rejects an enabled unseal nobody could use, as only authenticated members of the admin groups may unseal.
+func (u Unseal) validate(mode AuthMode) error {
+	if !u.Enabled {
+		return nil
+	}
+	if mode == AuthModeNone {
+		return errors.New("unseal requires authentication, as only members of the admin groups may unseal")
+	}
+	if len(u.AdminGroups) == 0 {
+		return errors.New("unseal requires admin groups, as only their members may unseal")
+	}
+	return nil
+}
+
 type Web struct {
 	Port    int    `yaml:"port"`
 	Context string `yaml:"context"`
@@ -120,29 +283,80 @@ type SealedSecrets struct {
 	Service   string `yaml:"service"`
 	Namespace string `yaml:"namespace"`
 	CertURL   string `yaml:"certURL,omitempty"`
+	// CertFile is a local certificate file used for sealing without cluster access.
+	CertFile string `yaml:"certFile,omitempty"`
+	// Cert is an inline PEM certificate used for sealing without cluster access.
+	Cert        string `yaml:"cert,omitempty"`
+	KubeContext string `yaml:"kubeContext,omitempty"`
+	// CertRefreshInterval defines how often the certificate is re-fetched to pick up rotated keys (0 disables).
+	// Controllers without an interval use the interval of sealedSecrets, so they can disable the refresh with 0.
+	CertRefreshInterval *time.Duration `yaml:"certRefreshInterval,omitempty"`
+}
+
+// RefreshInterval returns the certificate refresh interval, 0 if the refresh is disabled.
+func (ss SealedSecrets) RefreshInterval() time.Duration {
+	if ss.CertRefreshInterval == nil {
+		return 0
+	}
+	return *ss.CertRefreshInterval
+}
+
+// Offline returns true if the certificate is read from a file or inline PEM and no cluster access is needed.
+func (ss SealedSecrets) Offline() bool {
+	return ss.CertFile != "" || ss.Cert != ""
 }
 
 func (ss SealedSecrets) String() string {
+	if ss.Cert != "" {
+		return "Inline cert"
+	}
+	if ss.CertFile != "" {
+		return fmt.Sprintf("Cert file: %s", ss.CertFile)
+	}
 	if ss.CertURL != "" {
 		return fmt.Sprintf("Cert URL: %s", ss.CertURL)
 	}
+	if ss.KubeContext != "" {
+		return fmt.Sprintf("Context: %s / Namespace: %s / ServiceName: %s", ss.KubeContext, ss.Namespace, ss.Service)
+	}
 	return fmt.Sprintf("Namespace: %s / ServiceName: %s", ss.Namespace, ss.Service)
 }
 
+// DefaultController is the name of the controller if no controllers are configured.
+const DefaultController = "default"
+
+// Controller is a named sealed secrets controller.
+type Controller struct {
+	Name          string `yaml:"name"`
+	SealedSecrets `yaml:",inline"`
+}
+
 type flags struct {
-	disableLoadSecrets            *bool
-	enableWebLogs                 *bool
-	includeNamespaces             *string
-	kubesealArgs                  *string
-	sealedSecretsServiceName      *string
-	port                          *int
-	config                        *string
-	printVersion                  *bool
-	webContext                    *string
-	webExternalURL                *string
-	initialSecretFile             *string
-	sealedSecretsCertURL          *string
-	sealedSecretsServiceNamespace *string
+	disableLoadSecrets               *bool
+	enableCache                      *bool
+	impersonate                      *bool
+	accessReview                     *bool
+	authMode                         *string
+	authUserHeader                   *string
+	authGroupsHeader                 *string
+	authEmailHeader                  *string
+	trustedProxies                   *string
+	enableWebLogs                    *bool
+	includeNamespaces                *string
+	excludeNamespaces                *string
+	namespaceSelector                *string
+	kubesealArgs                     *string
+	sealedSecretsServiceName         *string
+	port                             *int
+	config                           *string
+	printVersion                     *bool
+	webContext                       *string
+	webExternalURL                   *string
+	initialSecretFile                *string
+	sealedSecretsCertURL             *string
+	sealedSecretsCertFile            *string
+	sealedSecretsServiceNamespace    *string
+	sealedSecretsCertRefreshInterval *time.Duration
 }
 
 func newFlags() *flags {
@@ -152,11 +366,61 @@ func newFlags() *flags {
 			false,
 			"Disable the loading of existing secrets",
 		),
+		enableCache: flag.Bool(
+			"enable-cache",
+			false,
+			"Serve sealed secrets and secrets from informer caches instead of calling the api server on each request",
+		),
+		impersonate: flag.Bool(
+			"impersonate",
+			false,
+			"Read sealed secrets and secrets impersonating the logged-in user, so the user's RBAC permissions apply",
+		),
+		accessReview: flag.Bool(
+			"access-review",
+			false,
+			"Check with subject access reviews if the logged-in user is allowed to get and list secrets",
+		),
+		authMode: flag.String(
+			"auth-mode",
+			string(AuthModeNone),
+			"How users are authenticated: none, oidc or header",
+		),
+		authUserHeader: flag.String(
+			"auth-user-header",
+			"",
+			"Header of the username set by the reverse proxy in the header auth mode (default X-Forwarded-User)",
+		),
+		authGroupsHeader: flag.String(
+			"auth-groups-header",
+			"",
+			"Header of the comma separated groups set by the reverse proxy in the header auth mode (default X-Forwarded-Groups)",
+		),
+		authEmailHeader: flag.String(
+			"auth-email-header",
+			"",
+			"Header of the email set by the reverse proxy in the header auth mode (default X-Forwarded-Email)",
+		),
+		trustedProxies: flag.String(
+			"trusted-proxies",
+			"",
+			"Space separated list of IPs and CIDRs of the reverse proxies whose user headers are trusted in the header auth mode",
+		),
 		enableWebLogs: flag.Bool("enable-web-logs", false, "Enable web logs"),
 		includeNamespaces: flag.String(
 			"include-namespaces",
 			"",
-			"Optional space separated list if namespaces to be included in the sealed secret search",
+			"Optional space separated list of namespaces to be included, as names, globs (team-*) or regular expressions (/team-.*/)",
+		),
+		excludeNamespaces: flag.String(
+			"exclude-namespaces",
+			"",
+			"Optional space separated list of namespaces to be excluded, as names, globs (team-*) or regular expressions (/team-.*/)",
+		),
+		namespaceSelector: flag.String(
+			"namespace-selector",
+			"",
+			"Optional label selector the namespaces have to match (e.g. sealed-secrets-web/enabled=true)",
 		),
 		kubesealArgs: flag.String(
 			"kubeseal-arguments",
@@ -178,6 +442,16 @@ func newFlags() *flags {
 			"",
 			"URL sealed secrets certificate (required if sealed secrets is not reachable with in cluster service)",
 		),
+		sealedSecretsCertRefreshInterval: flag.Duration(
+			"sealed-secrets-cert-refresh-interval",
+			time.Hour,
+			"Interval to refresh the sealed secrets certificate to pick up rotated keys (0 disables the refresh)",
+		),
+		sealedSecretsCertFile: flag.String(
+			"sealed-secrets-cert-file",
+			"",
+			"Local sealed secrets certificate file to seal offline without any cluster access",
+		),
 		initialSecretFile: flag.String(
 			"initial-secret-file",
 			"",
@@ -1,6 +1,10 @@
 package config
 
 import (
+	"os"
+	"path/filepath"
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 )
@@ -43,6 +47,22 @@ var _ = Describe("Types", func() {
 				ss.Service = "sealed-secrets-svc"
 				Ω(ss.String()).Should(Equal("Namespace: sealed-secrets / ServiceName: sealed-secrets-svc"))
 			})
+			It("should print the cert file", func() {
+				ss.CertFile = "cert.pem"
+				Ω(ss.String()).Should(Equal("Cert file: cert.pem"))
+				Ω(ss.Offline()).Should(BeTrue())
+			})
+			It("should print the inline cert", func() {
+				ss.Cert = "-----BEGIN CERTIFICATE-----"
+				Ω(ss.String()).Should(Equal("Inline cert"))
+				Ω(ss.Offline()).Should(BeTrue())
+			})
+			It("should print the kube context", func() {
+				ss.KubeContext = "prod"
+				ss.Namespace = "sealed-secrets"
+				ss.Service = "sealed-secrets-svc"
+				Ω(ss.String()).Should(Equal("Context: prod / Namespace: sealed-secrets / ServiceName: sealed-secrets-svc"))
+			})
 		})
 	})
 	Context("Parse", func() {
@@ -72,18 +92,159 @@ var _ = Describe("Types", func() {
 			Ω(cfg.SealedSecrets.Namespace).Should(Equal("namespace"))
 			Ω(cfg.SealedSecrets.Service).Should(Equal("name"))
 		})
+		It("should set the certificate refresh interval", func() {
+			d := 5 * time.Minute
+			f.sealedSecretsCertRefreshInterval = &d
+			cfg, err = parse(f)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.SealedSecrets.RefreshInterval()).Should(Equal(5 * time.Minute))
+		})
+		It("should use the cert file offline and disable loading secrets", func() {
+			f.sealedSecretsCertFile = ptr("cert.pem")
+			cfg, err = parse(f)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.SealedSecrets.CertFile).Should(Equal("cert.pem"))
+			Ω(cfg.Offline()).Should(BeTrue())
+			Ω(cfg.DisableLoadSecrets).Should(BeTrue())
+		})
+		It("should not be offline if a controller needs cluster access", func() {
+			f.sealedSecretsCertFile = ptr("cert.pem")
+			f.config = ptr("../../testdata/config-controllers.yaml")
+			cfg, err = parse(f)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.Offline()).Should(BeFalse())
+			Ω(cfg.DisableLoadSecrets).Should(BeFalse())
+		})
 		It("should set included namespaces correctly", func() {
 			f.includeNamespaces = ptr("foo bar")
 			cfg, err = parse(f)
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(cfg.IncludeNamespaces).Should(ContainElements("foo", "bar"))
 		})
+		It("should set excluded namespaces and the namespace selector", func() {
+			f.excludeNamespaces = ptr("kube-* /openshift-.*/")
+			f.namespaceSelector = ptr("team=a")
+			cfg, err = parse(f)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.ExcludeNamespaces).Should(Equal([]string{"kube-*", "/openshift-.*/"}))
+			Ω(cfg.NamespaceSelector).Should(Equal("team=a"))
+			Ω(cfg.NamespaceFilter().Matches("kube-system")).Should(BeFalse())
+		})
+		It("should fail for an invalid namespace selector", func() {
+			f.namespaceSelector = ptr("team in (")
+			_, err = parse(f)
+			Ω(err).Should(MatchError(ContainSubstring("invalid namespace selector 'team in ('")))
+		})
+		It("should not use the cache with impersonation", func() {
+			t := true
+			f.impersonate = &t
+			f.enableCache = &t
+			_, err = parse(f)
+			Ω(err).Should(MatchError(ContainSubstring("the cache can't be used with impersonation")))
+		})
+		Context("unseal", func() {
+			configFile := func(content string) *string {
+				path := filepath.Join(GinkgoT().TempDir(), "config.yaml")
+				Ω(os.WriteFile(path, []byte(content), 0o600)).Should(Succeed())
+				return &path
+			}
+
+			It("should fail without authentication", func() {
+				f.config = configFile("unseal:\n  enabled: true\n  adminGroups: [admins]\n")
+				_, err = parse(f)
+				Ω(err).Should(MatchError(ContainSubstring("unseal requires authentication")))
+			})
+			It("should fail without admin groups", func() {
+				f.config = configFile("unseal:\n  enabled: true\nauth:\n  mode: oidc\n")
+				_, err = parse(f)
+				Ω(err).Should(MatchError(ContainSubstring("unseal requires admin groups")))
+			})
+			It("should accept admin groups with authentication", func() {
+				f.config = configFile("unseal:\n  enabled: true\n  adminGroups: [admins]\nauth:\n  mode: oidc\n")
+				cfg, err = parse(f)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(cfg.Unseal.AdminGroups).Should(Equal([]string{"admins"}))
+			})
+			It("should not validate a disabled unseal", func() {
+				f.config = configFile("unseal:\n  enabled: false\n")
+				_, err = parse(f)
+				Ω(err).ShouldNot(HaveOccurred())
+			})
+		})
+		It("should disable the authentication by default", func() {
+			cfg, err = parse(f)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.Auth.Mode).Should(Equal(AuthModeNone))
+		})
+		It("should set the auth mode and the proxy headers", func() {
+			f.authMode = ptr("header")
+			f.authUserHeader = ptr("X-Auth-Request-User")
+			f.trustedProxies = ptr("10.0.0.0/8 127.0.0.1")
+			cfg, err = parse(f)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.Auth.Mode).Should(Equal(AuthModeHeader))
+			Ω(cfg.Auth.Header.UserHeader).Should(Equal("X-Auth-Request-User"))
+			Ω(cfg.Auth.Header.GroupsHeader).Should(Equal("X-Forwarded-Groups"))
+			Ω(cfg.Auth.Header.TrustedProxies).Should(Equal([]string{"10.0.0.0/8", "127.0.0.1"}))
+		})
+		It("should fail for an invalid auth mode", func() {
+			f.authMode = ptr("basic")
+			_, err = parse(f)
+			Ω(err).Should(MatchError("invalid auth mode 'basic'"))
+		})
 		It("should read the initial secrets file", func() {
 			f.initialSecretFile = &testConfigFile
 			cfg, err = parse(f)
 			Ω(err).ShouldNot(HaveOccurred())
 			Ω(cfg.InitialSecret).ShouldNot(BeEmpty())
 		})
+		It("should return the sealed secrets config as default controller", func() {
+			cfg, err = parse(f)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.GetControllers()).Should(HaveLen(1))
+			ctrl, ok := cfg.Controller("")
+			Ω(ok).Should(BeTrue())
+			Ω(ctrl.Name).Should(Equal(DefaultController))
+			Ω(ctrl.SealedSecrets).Should(Equal(cfg.SealedSecrets))
+		})
+		It("should read the controllers", func() {
+			f.config = ptr("../../testdata/config-controllers.yaml")
+			cfg, err = parse(f)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cfg.GetControllers()).Should(HaveLen(3))
+
+			ctrl, ok := cfg.Controller("")
+			Ω(ok).Should(BeTrue())
+			Ω(ctrl.Name).Should(Equal("dev"))
+			Ω(ctrl.Service).Should(Equal("sealed-secrets"))
+			Ω(ctrl.Namespace).Should(Equal("sealed-secrets"))
+			Ω(ctrl.RefreshInterval()).Should(Equal(time.Hour))
+
+			ctrl, ok = cfg.Controller("prod")
+			Ω(ok).Should(BeTrue())
+			Ω(ctrl.KubeContext).Should(Equal("prod-cluster"))
+			Ω(ctrl.Service).Should(Equal("sealed-secrets-controller"))
+			Ω(ctrl.Namespace).Should(Equal("kube-system"))
+			Ω(ctrl.RefreshInterval()).Should(Equal(10 * time.Minute))
+
+			ctrl, ok = cfg.Controller("offline")
+			Ω(ok).Should(BeTrue())
+			Ω(ctrl.CertURL).Should(Equal("https://sealed-secrets.example.com/v1/cert.pem"))
+			Ω(ctrl.RefreshInterval()).Should(BeZero())
+
+			_, ok = cfg.Controller("foo")
+			Ω(ok).Should(BeFalse())
+		})
+	})
+	Context("setupControllers", func() {
+		It("should fail if a controller has no name", func() {
+			err := setupControllers(&Config{Controllers: []Controller{{}}})
+			Ω(err).Should(MatchError("controller 0 has no name"))
+		})
+		It("should fail if a controller name is not unique", func() {
+			err := setupControllers(&Config{Controllers: []Controller{{Name: "a"}, {Name: "a"}}})
+			Ω(err).Should(MatchError("controller 'a' is defined more than once"))
+		})
 	})
 })
 
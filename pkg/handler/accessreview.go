@@ -0,0 +1,159 @@
+package handler
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gin-gonic/gin"
+	authv1 "k8s.io/api/authorization/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	authorizationv1 "k8s.io/client-go/kubernetes/typed/authorization/v1"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// defaultAccessReviewTTL is how long the decisions of the access reviews are cached.
+const defaultAccessReviewTTL = 30 * time.Second
+
+// BuildAccessReviewClient builds the client creating the subject access reviews.
+func BuildAccessReviewClient(clientConfig clientcmd.ClientConfig) (authorizationv1.SubjectAccessReviewInterface, error) {
+	conf, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := authorizationv1.NewForConfig(conf)
+	if err != nil {
+		return nil, err
+	}
+	return client.SubjectAccessReviews(), nil
+}
+
+// accessReviewer checks with subject access reviews if the session user is allowed to access secrets.
+// The decisions are cached per user, verb and namespace.
+type accessReviewer struct {
+	client    authorizationv1.SubjectAccessReviewInterface
+	ttl       time.Duration
+	now       func() time.Time
+	mu        sync.Mutex
+	decisions map[accessReviewKey]accessDecision
+}
+
+type accessReviewKey struct {
+	user      string
+	groups    string
+	verb      string
+	namespace string
+}
+
+type accessDecision struct {
+	allowed bool
+	expires time.Time
+}
+
+func newAccessReviewer(client authorizationv1.SubjectAccessReviewInterface) *accessReviewer {
+	return &accessReviewer{
+		client:    client,
+		ttl:       defaultAccessReviewTTL,
+		now:       time.Now,
+		decisions: make(map[accessReviewKey]accessDecision),
+	}
+}
+
+// allowed returns true if the user is allowed to use the verb on secrets in the namespace.
+// An empty namespace checks the permission in all namespaces.
+func (r *accessReviewer) allowed(ctx context.Context, user store.UserInfo, verb, namespace string) (bool, error) {
+	groups := slices.Clone(user.Groups)
+	slices.Sort(groups)
+	key := accessReviewKey{user: user.Username, groups: strings.Join(groups, "\n"), verb: verb, namespace: namespace}
+
+	now := r.now()
+	r.mu.Lock()
+	decision, ok := r.decisions[key]
+	r.mu.Unlock()
+	if ok && now.Before(decision.expires) {
+		return decision.allowed, nil
+	}
+
+	review, err := r.client.Create(ctx, &authv1.SubjectAccessReview{
+		Spec: authv1.SubjectAccessReviewSpec{
+			User:   user.Username,
+			Groups: user.Groups,
+			ResourceAttributes: &authv1.ResourceAttributes{
+				Namespace: namespace,
+				Verb:      verb,
+				Resource:  "secrets",
+			},
+		},
+	}, metav1.CreateOptions{})
+	if err != nil {
+		return false, err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for k, d := range r.decisions {
+		if !now.Before(d.expires) {
+			delete(r.decisions, k)
+		}
+	}
+	r.decisions[key] = accessDecision{allowed: review.Status.Allowed, expires: now.Add(r.ttl)}
+	return review.Status.Allowed, nil
+}
+
+// ReviewAccess checks the access of the session user to the secrets of the controller with subject access reviews.
+// An empty name selects the default controller, controllers added afterwards use the same client.
+func (h *SecretsHandler) ReviewAccess(name string, client authorizationv1.SubjectAccessReviewInterface) {
+	if name == "" {
+		h.reviewer = newAccessReviewer(client)
+		return
+	}
+	if ch, ok := h.controllers[name]; ok {
+		ch.reviewer = newAccessReviewer(client)
+	}
+}
+
+// reviewAccess returns an error if the session user is not allowed to use the verb on secrets in the namespace.
+func (h *SecretsHandler) reviewAccess(c *gin.Context, verb, namespace string) error {
+	if h.reviewer == nil {
+		return nil
+	}
+	session, ok := sessionData(c)
+	if ok {
+		allowed, err := h.reviewer.allowed(c, session.UserInfo, verb, namespace)
+		if err != nil {
+			return err
+		}
+		if allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("user '%s' is %w to %s secrets in namespace '%s'", sessionUser(c), errNamespaceNotAllowed, verb, namespace)
+}
+
+// reviewedNamespaces returns a predicate for the namespaces in which the session user is allowed to use the verb
+// on secrets. It returns nil if access reviews are disabled or the user is allowed to use the verb in all namespaces.
+func (h *SecretsHandler) reviewedNamespaces(c *gin.Context, verb string) (func(namespace string) bool, error) {
+	if h.reviewer == nil {
+		return nil, nil
+	}
+	session, ok := sessionData(c)
+	if !ok {
+		return func(string) bool { return false }, nil
+	}
+	allowed, err := h.reviewer.allowed(c, session.UserInfo, verb, "")
+	if err != nil || allowed {
+		return nil, err
+	}
+	return func(namespace string) bool {
+		allowed, err := h.reviewer.allowed(c, session.UserInfo, verb, namespace)
+		if err != nil {
+			log.Printf("Error reviewing the access of %s to namespace %s: %v\n", session.UserInfo.Username, namespace, err)
+		}
+		return allowed
+	}, nil
+}
@@ -0,0 +1,156 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	authv1 "k8s.io/api/authorization/v1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("AccessReview", func() {
+		var (
+			recorder *httptest.ResponseRecorder
+			c        *gin.Context
+			h        *SecretsHandler
+			reviews  []authv1.SubjectAccessReviewSpec
+			now      time.Time
+		)
+		newContext := func(url string) {
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Request, _ = http.NewRequest("GET", url, nil)
+			c.Set("user_session", &store.SessionData{UserInfo: store.UserInfo{
+				Username: "alice",
+				Groups:   []string{"team-a"},
+			}})
+		}
+
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			reviews = nil
+			now = time.Now()
+
+			coreClient := fake.NewSimpleClientset(
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+				&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "one"}},
+				&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "two"}},
+			)
+			// alice may only get and list the secrets of team-a
+			coreClient.PrependReactor("create", "subjectaccessreviews",
+				func(action k8stesting.Action) (bool, runtime.Object, error) {
+					review := action.(k8stesting.CreateAction).GetObject().(*authv1.SubjectAccessReview)
+					reviews = append(reviews, review.Spec)
+					review.Status.Allowed = review.Spec.User == "alice" && review.Spec.ResourceAttributes.Namespace == "team-a"
+					return true, review, nil
+				})
+			ssClient := ssfake.NewSimpleClientset(
+				&v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "one"}},
+				&v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "two"}},
+			)
+			metaClient := newMetadataClient(secretMetadata("team-a", "one"), secretMetadata("team-b", "two"))
+			h = NewHandler(coreClient.CoreV1(), ssClient.BitnamiV1alpha1(), metaClient, &config.Config{})
+			h.ReviewAccess("", coreClient.AuthorizationV1().SubjectAccessReviews())
+			h.reviewer.now = func() time.Time { return now }
+		})
+
+		It("should return the secret if the user is allowed to get it", func() {
+			newContext("/api/secret/team-a/one")
+			c.Params = gin.Params{{Key: "namespace", Value: "team-a"}, {Key: "name", Value: "one"}}
+			h.Secret(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(reviews).Should(Equal([]authv1.SubjectAccessReviewSpec{{
+				User:   "alice",
+				Groups: []string{"team-a"},
+				ResourceAttributes: &authv1.ResourceAttributes{
+					Namespace: "team-a",
+					Verb:      "get",
+					Resource:  "secrets",
+				},
+			}}))
+		})
+
+		It("should not return the secret if the user is not allowed to get it", func() {
+			newContext("/api/secret/team-b/two")
+			c.Params = gin.Params{{Key: "namespace", Value: "team-b"}, {Key: "name", Value: "two"}}
+			h.Secret(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"user 'alice' is not allowed to get secrets in namespace 'team-b'"}`))
+		})
+
+		It("should not return the secret without session", func() {
+			newContext("/api/secret/team-a/one")
+			c.Keys = nil
+			c.Params = gin.Params{{Key: "namespace", Value: "team-a"}, {Key: "name", Value: "one"}}
+			h.Secret(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(reviews).Should(BeEmpty())
+		})
+
+		It("should only list the namespaces the user is allowed to list", func() {
+			newContext("/api/secrets")
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			var result SecretList
+			Ω(json.Unmarshal(recorder.Body.Bytes(), &result)).Should(Succeed())
+			Ω(result.Secrets).Should(HaveLen(1))
+			Ω(result.Secrets[0].Namespace).Should(Equal("team-a"))
+			// all namespaces, team-a and team-b
+			Ω(reviews).Should(HaveLen(3))
+			Ω(reviews[0].ResourceAttributes.Namespace).Should(BeEmpty())
+		})
+
+		It("should not list a namespace the user is not allowed to list", func() {
+			newContext("/api/secrets?namespace=team-b")
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"user 'alice' is not allowed to list secrets in namespace 'team-b'"}`))
+		})
+
+		It("should cache the decisions", func() {
+			for range 3 {
+				newContext("/api/secrets?namespace=team-a")
+				h.AllSecrets(c)
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+			}
+			Ω(reviews).Should(HaveLen(1))
+
+			now = now.Add(defaultAccessReviewTTL)
+			newContext("/api/secrets?namespace=team-a")
+			h.AllSecrets(c)
+			Ω(reviews).Should(HaveLen(2))
+		})
+
+		It("should not share the decisions between users", func() {
+			newContext("/api/secrets?namespace=team-a")
+			h.AllSecrets(c)
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+
+			newContext("/api/secrets?namespace=team-a")
+			c.Set("user_session", &store.SessionData{UserInfo: store.UserInfo{Username: "bob", Groups: []string{"team-a"}}})
+			h.AllSecrets(c)
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(reviews).Should(HaveLen(2))
+		})
+	})
+})
@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+// authorize returns an error if the policy does not allow the operation in the namespace to the user of the session.
+// The empty namespace stands for all namespaces.
+func authorize(c *gin.Context, policy *config.Policy, op config.Operation, namespace string) error {
+	if !policy.Enabled() {
+		return nil
+	}
+	if session, ok := sessionData(c); ok && policy.Allowed(session.UserInfo.Groups, op, namespace) {
+		return nil
+	}
+	if namespace == "" {
+		return fmt.Errorf("user '%s' is %w to %s cluster-wide secrets", sessionUser(c), errNamespaceNotAllowed, op)
+	}
+	return fmt.Errorf("user '%s' is %w to %s secrets in namespace '%s'", sessionUser(c), errNamespaceNotAllowed, op, namespace)
+}
+
+// allNamespaces returns a predicate for the namespaces matching all non nil predicates.
+// It returns nil if all predicates are nil.
+func allNamespaces(predicates ...func(namespace string) bool) func(namespace string) bool {
+	predicates = slices.DeleteFunc(predicates, func(p func(string) bool) bool { return p == nil })
+	if len(predicates) == 0 {
+		return nil
+	}
+	return func(namespace string) bool {
+		for _, p := range predicates {
+			if !p(namespace) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// allowedNamespaces returns a predicate for the namespaces in which the policy allows the operation
+// to the user of the session. It returns nil if the policy is disabled.
+func allowedNamespaces(c *gin.Context, policy *config.Policy, op config.Operation) func(namespace string) bool {
+	if !policy.Enabled() {
+		return nil
+	}
+	session, ok := sessionData(c)
+	return func(namespace string) bool {
+		return ok && policy.Allowed(session.UserInfo.Groups, op, namespace)
+	}
+}
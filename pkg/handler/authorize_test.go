@@ -0,0 +1,219 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gattma/sealed-secrets-web/pkg/mocks/seal"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("Authorization", func() {
+		var (
+			recorder *httptest.ResponseRecorder
+			c        *gin.Context
+			cfg      *config.Config
+			sh       *SecretsHandler
+		)
+		newContext := func(method, url, body string) {
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Request, _ = http.NewRequest(method, url, bytes.NewReader([]byte(body)))
+			c.Set("user_session", &store.SessionData{UserInfo: store.UserInfo{
+				Username: "alice",
+				Groups:   []string{"team-a"},
+			}})
+		}
+
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			cfg = &config.Config{Authorization: config.Authorization{Rules: []config.AuthorizationRule{
+				{Groups: []string{"team-a"}, Namespaces: []string{"team-a"}},
+				{Groups: []string{"team-a"}, Namespaces: []string{"shared"}, Operations: []config.Operation{config.OperationList}},
+			}}}
+			coreClient := fake.NewSimpleClientset(
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a"}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b"}},
+				&v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "shared"}},
+				&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "one"}},
+				&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "three"}},
+			)
+			ssClient := ssfake.NewSimpleClientset(
+				&v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "one"}},
+				&v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-b", Name: "two"}},
+				&v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "shared", Name: "three"}},
+			)
+			metaClient := newMetadataClient(secretMetadata("team-a", "one"), secretMetadata("shared", "three"))
+			sh = NewHandler(coreClient.CoreV1(), ssClient.BitnamiV1alpha1(), metaClient, cfg)
+		})
+
+		It("should only list the namespaces the user is allowed to list", func() {
+			newContext("GET", "/api/secrets", "")
+			sh.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			var result SecretList
+			Ω(json.Unmarshal(recorder.Body.Bytes(), &result)).Should(Succeed())
+			Ω(result.Secrets).Should(HaveLen(2))
+			Ω(result.Secrets[0].Namespace).Should(Equal("shared"))
+			Ω(result.Secrets[1].Namespace).Should(Equal("team-a"))
+		})
+
+		It("should not list a namespace the user is not allowed to list", func() {
+			newContext("GET", "/api/secrets?namespace=team-b", "")
+			sh.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(
+				`{"error":"user 'alice' is not allowed to list secrets in namespace 'team-b'"}`,
+			))
+		})
+
+		It("should not list secrets without session", func() {
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Request, _ = http.NewRequest("GET", "/api/secrets", nil)
+			sh.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(`{"secrets":[],"total":0}`))
+		})
+
+		It("should return the secret of an allowed namespace", func() {
+			newContext("GET", "/api/secret/team-a/one", "")
+			c.Params = gin.Params{{Key: "namespace", Value: "team-a"}, {Key: "name", Value: "one"}}
+			sh.Secret(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+		})
+
+		It("should not return the secret if the user is only allowed to list", func() {
+			newContext("GET", "/api/secret/shared/three", "")
+			c.Params = gin.Params{{Key: "namespace", Value: "shared"}, {Key: "name", Value: "three"}}
+			sh.Secret(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(
+				`{"error":"user 'alice' is not allowed to read secrets in namespace 'shared'"}`,
+			))
+		})
+
+		It("should not return the sealed secret of other namespaces", func() {
+			newContext("GET", "/api/sealedsecret/team-b/two", "")
+			c.Params = gin.Params{{Key: "namespace", Value: "team-b"}, {Key: "name", Value: "two"}}
+			sh.SealedSecret(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+		})
+
+		Context("Handler", func() {
+			var (
+				mock   *gomock.Controller
+				sealer *seal.MockSealer
+				h      *Handler
+			)
+			BeforeEach(func() {
+				mock = gomock.NewController(GinkgoT())
+				sealer = seal.NewMockSealer(mock)
+				h = &Handler{sealer: sealer, namespaces: sh, policy: cfg.Policy(), cfg: cfg}
+			})
+
+			It("should seal values of allowed namespaces", func() {
+				newContext("POST", "/api/raw", `{"name":"foo","namespace":"team-a","value":"bar"}`)
+				c.Request.Header.Set("Content-Type", "application/json")
+
+				sealer.EXPECT().Raw(gomock.Any()).Return([]byte("foo"), nil)
+
+				h.Raw(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+			})
+
+			It("should not seal secrets of namespaces the user is not allowed to seal", func() {
+				newContext("POST", "/api/kubeseal",
+					`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo","namespace":"shared"}}`)
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				h.KubeSeal(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(ContainSubstring(
+					"user 'alice' is not allowed to seal secrets in namespace 'shared'",
+				))
+			})
+
+			It("should not seal cluster-wide secrets if the user is not allowed to seal in all namespaces", func() {
+				newContext("POST", "/api/kubeseal?scope=cluster-wide",
+					`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo"}}`)
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				h.KubeSeal(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(ContainSubstring("user 'alice' is not allowed to seal cluster-wide secrets"))
+			})
+
+			It("should not seal secrets without a namespace for a default namespace the user is not allowed to seal", func() {
+				newContext("POST", "/api/kubeseal", `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo"}}`)
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				sealer.EXPECT().DefaultNamespace().Return("team-b", nil)
+
+				h.KubeSeal(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(ContainSubstring(
+					"user 'alice' is not allowed to seal secrets in namespace 'team-b'",
+				))
+			})
+
+			It("should not seal raw values without a namespace if the user is not allowed to seal in all namespaces", func() {
+				newContext("POST", "/api/raw", `{"name":"foo","value":"bar"}`)
+				c.Request.Header.Set("Content-Type", "application/json")
+
+				h.Raw(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"user 'alice' is not allowed to seal cluster-wide secrets"}`))
+			})
+
+			It("should not re-encrypt cluster-wide sealed secrets if the user is not allowed to seal in all namespaces", func() {
+				newContext("POST", "/api/reencrypt", "metadata:\n  name: foo\n  annotations:\n"+
+					"    sealedsecrets.bitnami.com/cluster-wide: \"true\"\n")
+				c.Request.Header.Set("Accept", "application/json")
+
+				h.ReEncrypt(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"user 'alice' is not allowed to seal cluster-wide secrets"}`))
+			})
+
+			It("should not validate sealed secrets of namespaces the user is not allowed to validate", func() {
+				newContext("POST", "/api/validate", "metadata:\n  namespace: team-b\n")
+
+				h.Validate(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal(
+					"user 'alice' is not allowed to validate secrets in namespace 'team-b'",
+				))
+			})
+		})
+	})
+})
@@ -0,0 +1,154 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"sort"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssClient "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/typed/sealedsecrets/v1alpha1"
+	ssListers "github.com/bitnami-labs/sealed-secrets/pkg/client/listers/sealedsecrets/v1alpha1"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	listersv1 "k8s.io/client-go/listers/core/v1"
+	"k8s.io/client-go/tools/cache"
+)
+
+// errNotCached is returned if the namespace is not watched by the cache.
+var errNotCached = errors.New("not cached")
+
+// secretsCache keeps the sealed secrets and secrets in informer caches.
+// There is one informer per included namespace, or a single one for all namespaces with the key "".
+type secretsCache struct {
+	sealedSecrets map[string]cache.SharedIndexInformer
+	secrets       map[string]cache.SharedIndexInformer
+}
+
+func newSecretsCache(
+	coreClient corev1.CoreV1Interface,
+	ssCl ssClient.BitnamiV1alpha1Interface,
+	namespaces []string,
+) *secretsCache {
+	c := &secretsCache{
+		sealedSecrets: make(map[string]cache.SharedIndexInformer, len(namespaces)),
+		secrets:       make(map[string]cache.SharedIndexInformer, len(namespaces)),
+	}
+	indexers := cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}
+	for _, ns := range namespaces {
+		c.sealedSecrets[ns] = cache.NewSharedIndexInformer(&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return ssCl.SealedSecrets(ns).List(context.Background(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return ssCl.SealedSecrets(ns).Watch(context.Background(), opts)
+			},
+		}, &v1alpha1.SealedSecret{}, 0, indexers)
+		c.secrets[ns] = cache.NewSharedIndexInformer(&cache.ListWatch{
+			ListFunc: func(opts metav1.ListOptions) (runtime.Object, error) {
+				return coreClient.Secrets(ns).List(context.Background(), opts)
+			},
+			WatchFunc: func(opts metav1.ListOptions) (watch.Interface, error) {
+				return coreClient.Secrets(ns).Watch(context.Background(), opts)
+			},
+		}, &v1.Secret{}, 0, indexers)
+	}
+	return c
+}
+
+// start runs the informers until the context is done.
+func (c *secretsCache) start(ctx context.Context) {
+	for _, inf := range c.sealedSecrets {
+		go inf.Run(ctx.Done())
+	}
+	for _, inf := range c.secrets {
+		go inf.Run(ctx.Done())
+	}
+}
+
+// synced returns true if all informers have synced. Until then, the api server is called directly.
+func (c *secretsCache) synced() bool {
+	for _, inf := range c.sealedSecrets {
+		if !inf.HasSynced() {
+			return false
+		}
+	}
+	for _, inf := range c.secrets {
+		if !inf.HasSynced() {
+			return false
+		}
+	}
+	return true
+}
+
+// informerFor returns the informer watching the given namespace.
+func informerFor(informers map[string]cache.SharedIndexInformer, ns string) (cache.SharedIndexInformer, bool) {
+	if inf, ok := informers[ns]; ok {
+		return inf, true
+	}
+	inf, ok := informers[""]
+	return inf, ok
+}
+
+// listSealedSecrets returns the sealed secrets of the namespace sorted by namespace and name.
+// An empty namespace returns the sealed secrets of all watched namespaces.
+// Like the api server, only the fields metadata.name and metadata.namespace can be selected.
+func (c *secretsCache) listSealedSecrets(
+	ns string,
+	labelSelector labels.Selector,
+	fieldSelector fields.Selector,
+) []v1alpha1.SealedSecret {
+	var items []*v1alpha1.SealedSecret
+	if ns == "" {
+		for _, inf := range c.sealedSecrets {
+			list, _ := ssListers.NewSealedSecretLister(inf.GetIndexer()).List(labelSelector)
+			items = append(items, list...)
+		}
+	} else if inf, ok := informerFor(c.sealedSecrets, ns); ok {
+		items, _ = ssListers.NewSealedSecretLister(inf.GetIndexer()).SealedSecrets(ns).List(labelSelector)
+	}
+
+	result := make([]v1alpha1.SealedSecret, 0, len(items))
+	for _, item := range items {
+		if fieldSelector.Matches(fields.Set{"metadata.name": item.Name, "metadata.namespace": item.Namespace}) {
+			result = append(result, *item.DeepCopy())
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace == result[j].Namespace {
+			return result[i].Name < result[j].Name
+		}
+		return result[i].Namespace < result[j].Namespace
+	})
+	return result
+}
+
+// getSealedSecret returns a copy of the cached sealed secret.
+func (c *secretsCache) getSealedSecret(ns, name string) (*v1alpha1.SealedSecret, error) {
+	inf, ok := informerFor(c.sealedSecrets, ns)
+	if !ok {
+		return nil, errNotCached
+	}
+	ss, err := ssListers.NewSealedSecretLister(inf.GetIndexer()).SealedSecrets(ns).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return ss.DeepCopy(), nil
+}
+
+// getSecret returns a copy of the cached secret.
+func (c *secretsCache) getSecret(ns, name string) (*v1.Secret, error) {
+	inf, ok := informerFor(c.secrets, ns)
+	if !ok {
+		return nil, errNotCached
+	}
+	secret, err := listersv1.NewSecretLister(inf.GetIndexer()).Secrets(ns).Get(name)
+	if err != nil {
+		return nil, err
+	}
+	return secret.DeepCopy(), nil
+}
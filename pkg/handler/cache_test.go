@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("Cache", func() {
+		var (
+			recorder *httptest.ResponseRecorder
+			c        *gin.Context
+			ctx      context.Context
+			cancel   context.CancelFunc
+			ssClient *ssfake.Clientset
+			h        *SecretsHandler
+		)
+		sealedSecret := func(ns, name string, labels map[string]string) *v1alpha1.SealedSecret {
+			return &v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, Labels: labels}}
+		}
+		secret := func(ns, name string) *v1.Secret {
+			return &v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name, UID: "1234"},
+				Data:       map[string][]byte{"username": []byte("admin")},
+			}
+		}
+		list := func(query string) SecretList {
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Request, _ = http.NewRequest("GET", "/api/secrets"+query, nil)
+			h.AllSecrets(c)
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			var result SecretList
+			Ω(json.Unmarshal(recorder.Body.Bytes(), &result)).Should(Succeed())
+			return result
+		}
+		names := func(result SecretList) []string {
+			var n []string
+			for _, s := range result.Secrets {
+				n = append(n, s.Namespace+"/"+s.Name)
+			}
+			return n
+		}
+
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			ctx, cancel = context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			ssClient = ssfake.NewSimpleClientset(
+				sealedSecret("a", "one", map[string]string{"app": "web"}),
+				sealedSecret("a", "two", nil),
+				sealedSecret("b", "three", map[string]string{"app": "web"}),
+				sealedSecret("c", "four", nil),
+			)
+			coreClient := fake.NewSimpleClientset(secret("a", "one"), secret("b", "three"), secret("c", "four"))
+			h = NewHandler(coreClient.CoreV1(), ssClient.BitnamiV1alpha1(), nil, &config.Config{
+				IncludeNamespaces: []string{"a", "b"},
+			})
+			h.StartCache(ctx)
+			Eventually(h.cached).Should(BeTrue())
+		})
+
+		It("should be ready when the caches are synced", func() {
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Request, _ = http.NewRequest("GET", "/_ready", nil)
+			h.Ready(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+		})
+
+		It("should list the secrets of the included namespaces from the cache", func() {
+			result := list("")
+
+			Ω(names(result)).Should(Equal([]string{"a/one", "a/two", "b/three"}))
+			Ω(result.Secrets[0].SecretExists).Should(HaveValue(BeTrue()))
+			Ω(result.Secrets[1].SecretExists).Should(HaveValue(BeFalse()))
+			Ω(result.Total).Should(HaveValue(Equal(int64(3))))
+		})
+
+		It("should select and page the cached secrets", func() {
+			result := list("?labelSelector=app%3Dweb&limit=1")
+			Ω(names(result)).Should(Equal([]string{"a/one"}))
+			Ω(result.Total).Should(HaveValue(Equal(int64(2))))
+			Ω(result.Continue).ShouldNot(BeEmpty())
+
+			result = list("?labelSelector=app%3Dweb&limit=1&continue=" + result.Continue)
+			Ω(names(result)).Should(Equal([]string{"b/three"}))
+			Ω(result.Continue).Should(BeEmpty())
+
+			result = list("?fieldSelector=metadata.name%3Dtwo")
+			Ω(names(result)).Should(Equal([]string{"a/two"}))
+		})
+
+		It("should filter the cached secrets before paging and count all matching secrets", func() {
+			result := list("?name=e&secretExists=true&limit=1")
+			Ω(names(result)).Should(Equal([]string{"a/one"}))
+			Ω(result.Total).Should(HaveValue(Equal(int64(2))))
+
+			result = list("?name=e&secretExists=true&limit=1&continue=" + result.Continue)
+			Ω(names(result)).Should(Equal([]string{"b/three"}))
+			Ω(result.Continue).Should(BeEmpty())
+		})
+
+		It("should pick up changes", func() {
+			_, err := ssClient.BitnamiV1alpha1().SealedSecrets("b").Create(ctx, sealedSecret("b", "five", nil), metav1.CreateOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Eventually(func() []string {
+				return names(list("?namespace=b"))
+			}).Should(Equal([]string{"b/five", "b/three"}))
+		})
+
+		It("should get the secret from the cache", func() {
+			s, err := h.GetSecret(ctx, "a", "one")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(s.Data).Should(HaveKeyWithValue("username", []byte("admin")))
+			Ω(s.UID).Should(BeEmpty())
+
+			cached, err := h.cache.getSecret("a", "one")
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cached.UID).ShouldNot(BeEmpty())
+		})
+
+		It("should fall back to the api server if the caches are not synced", func() {
+			h.cache = newSecretsCache(h.coreClient, h.ssClient, []string{"a"})
+
+			Ω(names(list("?namespace=b"))).Should(Equal([]string{"b/three"}))
+			Ω(names(list(""))).Should(Equal([]string{"a/one", "a/two", "b/three"}))
+
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Request, _ = http.NewRequest("GET", "/_ready", nil)
+			h.Ready(c)
+			Ω(recorder.Code).Should(Equal(http.StatusServiceUnavailable))
+		})
+	})
+})
@@ -8,7 +8,13 @@ import (
 )
 
 func (h *Handler) Certificate(c *gin.Context) {
-	certificate, err := h.sealer.Certificate(c)
+	sealer, err := h.sealerFor(c)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	certificate, err := sealer.Certificate(c)
 	if err != nil {
 		log.Printf("Error in reading Certificate %s\n", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -16,3 +22,26 @@ func (h *Handler) Certificate(c *gin.Context) {
 	}
 	c.Data(http.StatusOK, "text/plain; charset=utf-8", certificate)
 }
+
+func (h *Handler) CertificateInfo(c *gin.Context) {
+	contentType, _, done := NegotiateFormat(c)
+	if done {
+		return
+	}
+	sealer, err := h.sealerFor(c)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	info, err := sealer.CertificateInfo(c)
+	if err != nil {
+		log.Printf("Error in reading Certificate %s\n", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	contextNegotiate(c, http.StatusOK, gin.Negotiate{
+		Offered: []string{contentType},
+		Data:    info,
+	})
+}
@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 
 	"github.com/gattma/sealed-secrets-web/pkg/mocks/seal"
+	ssw "github.com/gattma/sealed-secrets-web/pkg/seal"
 	"github.com/gin-gonic/gin"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -47,5 +48,47 @@ var _ = Describe("Handler ", func() {
 			Ω(recorder.Body.String()).Should(Equal(`{"error":"unexpected error"}`))
 			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/json; charset=utf-8"))
 		})
+		It("should return the certificate info as json", func() {
+			c.Request, _ = http.NewRequest("GET", "/v1/certificate/info", nil)
+			c.Request.Header.Set("Accept", "application/json")
+			sealer.EXPECT().CertificateInfo(gomock.Any()).Return(certificateInfo(), nil)
+			h.CertificateInfo(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(ContainSubstring(`"fingerprint":"SHA256:abc"`))
+			Ω(recorder.Body.String()).Should(ContainSubstring(`"sealingKeyCurrent":false`))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/json; charset=utf-8"))
+		})
+		It("should return the certificate info as yaml", func() {
+			c.Request, _ = http.NewRequest("GET", "/v1/certificate/info", nil)
+			c.Request.Header.Set("Accept", "application/yaml")
+			sealer.EXPECT().CertificateInfo(gomock.Any()).Return(certificateInfo(), nil)
+			h.CertificateInfo(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(ContainSubstring("fingerprint: SHA256:abc\n"))
+			Ω(recorder.Body.String()).Should(ContainSubstring("daysUntilExpiry: 10\n"))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/yaml; charset=utf-8"))
+		})
+		It("should fail when requesting the certificate info", func() {
+			c.Request, _ = http.NewRequest("GET", "/v1/certificate/info", nil)
+			c.Request.Header.Set("Accept", "application/json")
+			sealer.EXPECT().CertificateInfo(gomock.Any()).Return(nil, fmt.Errorf("unexpected error"))
+			h.CertificateInfo(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusInternalServerError))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"unexpected error"}`))
+		})
 	})
 })
+
+func certificateInfo() *ssw.CertificateInfo {
+	return &ssw.CertificateInfo{
+		Subject:               "CN=sealed-secret",
+		Fingerprint:           "SHA256:abc",
+		KeySize:               4096,
+		DaysUntilExpiry:       10,
+		ExpiresSoon:           true,
+		SealingKeyFingerprint: "SHA256:def",
+	}
+}
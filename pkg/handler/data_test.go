@@ -46,5 +46,38 @@ kind: Secret
 metadata:
   creationTimestamp: null
 type: Opaque
+`
+
+	multiDocAsYAML = `apiVersion: v1
+kind: Secret
+metadata:
+  name: first
+stringData:
+  username: admin
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  name: second
+data:
+  password: YWRtaW4=
+`
+	listAsJSON = `{
+  "apiVersion": "v1",
+  "kind": "List",
+  "items": [
+    {"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "first"}, "stringData": {"username": "admin"}},
+    {"apiVersion": "v1", "kind": "Secret", "metadata": {"name": "second"}, "data": {"password": "YWRtaW4="}}
+  ]
+}`
+	invalidMultiDocAsYAML = `apiVersion: v1
+kind: Secret
+metadata:
+  name: first
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: second
 `
 )
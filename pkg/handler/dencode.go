@@ -1,14 +1,11 @@
 package handler
 
 import (
-	"io"
 	"log"
 	"net/http"
 
-	"github.com/bitnami-labs/sealed-secrets/pkg/multidocyaml"
 	"github.com/gin-gonic/gin"
 	v1 "k8s.io/api/core/v1"
-	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/kubernetes/scheme"
 )
 
@@ -18,14 +15,24 @@ func (h *Handler) Dencode(c *gin.Context) {
 		return
 	}
 
-	secret, err := readSecret(scheme.Codecs.UniversalDecoder(), c.Request.Body)
+	docs, err := readSecrets(scheme.Codecs.UniversalDecoder(), c.Request.Body)
 	if err != nil {
 		log.Printf("Error in %s: %s\n", Sanitize(c.Request.URL.Path), Sanitize(err.Error()))
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		return
 	}
 
-	encode, err := encodeSecret(h.dencode(secret), outputFormat)
+	encoded := make([][]byte, len(docs.secrets))
+	for i, secret := range docs.secrets {
+		if encoded[i], err = encodeSecret(h.dencode(secret), outputFormat); err != nil {
+			err = docs.documentError(i, err)
+			log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	encode, err := docs.join(encoded, outputFormat)
 	if err != nil {
 		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -57,21 +64,3 @@ func (h *Handler) dencode(secret *v1.Secret) *v1.Secret {
 	}
 	return secret
 }
-
-func readSecret(codec runtime.Decoder, r io.Reader) (*v1.Secret, error) {
-	data, err := io.ReadAll(r)
-	if err != nil {
-		return nil, err
-	}
-
-	if err := multidocyaml.EnsureNotMultiDoc(data); err != nil {
-		return nil, err
-	}
-
-	var ret v1.Secret
-	if err = runtime.DecodeInto(codec, data, &ret); err != nil {
-		return nil, err
-	}
-
-	return &ret, nil
-}
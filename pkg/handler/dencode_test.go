@@ -63,6 +63,67 @@ var _ = Describe("Handler ", func() {
 			Ω(recorder.Body.String()).Should(Equal(stringDataAsYAML))
 			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/yaml"))
 		})
+		It("should dencode multi document yaml in the same order", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/dencode", bytes.NewReader([]byte(multiDocAsYAML)))
+			c.Request.Header.Set("Content-Type", "application/yaml")
+			c.Request.Header.Set("Accept", "application/yaml")
+			h.Dencode(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(`---
+apiVersion: v1
+data:
+  username: YWRtaW4=
+kind: Secret
+metadata:
+  creationTimestamp: null
+  name: first
+---
+apiVersion: v1
+kind: Secret
+metadata:
+  creationTimestamp: null
+  name: second
+stringData:
+  password: admin
+`))
+		})
+		It("should dencode a list and output a list", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/dencode", bytes.NewReader([]byte(listAsJSON)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/yaml")
+			h.Dencode(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(`apiVersion: v1
+items:
+- apiVersion: v1
+  data:
+    username: YWRtaW4=
+  kind: Secret
+  metadata:
+    creationTimestamp: null
+    name: first
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    creationTimestamp: null
+    name: second
+  stringData:
+    password: admin
+kind: List
+metadata: {}
+`))
+		})
+		It("should name the failing document", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/dencode", bytes.NewReader([]byte(invalidMultiDocAsYAML)))
+			c.Request.Header.Set("Content-Type", "application/yaml")
+			c.Request.Header.Set("Accept", "application/json")
+			h.Dencode(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusUnprocessableEntity))
+			Ω(recorder.Body.String()).Should(ContainSubstring(`{"error":"document 1:`))
+		})
 		It("should encode input as json and output as text not acceptable", func() {
 			c.Request, _ = http.NewRequest("POST", "/v1/dencode", bytes.NewReader([]byte(dataAsJSON)))
 			c.Request.Header.Set("Content-Type", "application/json")
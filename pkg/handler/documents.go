@@ -0,0 +1,153 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// secretDocuments are the secrets read from a (multi document) yaml, a json stream or a v1.List.
+type secretDocuments struct {
+	secrets []*v1.Secret
+	// list is true if the secrets were read from a v1.List
+	list bool
+}
+
+// readSecrets reads all secrets in document order. v1.List documents are expanded to their items.
+func readSecrets(codec runtime.Decoder, r io.Reader) (*secretDocuments, error) {
+	docs := &secretDocuments{}
+	list, err := readDocuments(codec, r, func(data []byte) error {
+		secret, err := decodeSecret(codec, data)
+		if err != nil {
+			return err
+		}
+		docs.secrets = append(docs.secrets, secret)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(docs.secrets) == 0 {
+		return nil, errors.New("no secrets found")
+	}
+	docs.list = list
+	return docs, nil
+}
+
+// readObjectsMetadata reads the metadata of all objects in document order. v1.List documents are expanded to their items.
+func readObjectsMetadata(codec runtime.Decoder, r io.Reader) ([]*metav1.PartialObjectMetadata, error) {
+	var objects []*metav1.PartialObjectMetadata
+	_, err := readDocuments(codec, r, func(data []byte) error {
+		var meta metav1.PartialObjectMetadata
+		if err := json.Unmarshal(data, &meta); err != nil {
+			return err
+		}
+		objects = append(objects, &meta)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	if len(objects) == 0 {
+		return nil, errors.New("no objects found")
+	}
+	return objects, nil
+}
+
+// readDocuments calls decode with the json of each (multi document) yaml, json stream or v1.List document
+// in document order. v1.List documents are expanded to their items, list is true if there was one.
+func readDocuments(codec runtime.Decoder, r io.Reader, decode func(data []byte) error) (list bool, err error) {
+	decoder := yaml.NewYAMLOrJSONDecoder(r, 4096)
+	for i := 0; ; i++ {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			return false, fmt.Errorf("document %d: %w", i, err)
+		}
+		data := bytes.TrimSpace(raw.Raw)
+		if len(data) == 0 || string(data) == "null" {
+			i--
+			continue
+		}
+
+		var tm metav1.TypeMeta
+		if err := json.Unmarshal(data, &tm); err != nil {
+			return false, fmt.Errorf("document %d: %w", i, err)
+		}
+		if tm.Kind != "List" {
+			if err := decode(data); err != nil {
+				return false, fmt.Errorf("document %d: %w", i, err)
+			}
+			continue
+		}
+
+		var items v1.List
+		if err := runtime.DecodeInto(codec, data, &items); err != nil {
+			return false, fmt.Errorf("document %d: %w", i, err)
+		}
+		list = true
+		for j, item := range items.Items {
+			if err := decode(item.Raw); err != nil {
+				return false, fmt.Errorf("document %d item %d: %w", i, j, err)
+			}
+		}
+	}
+	return list, nil
+}
+
+func decodeSecret(codec runtime.Decoder, data []byte) (*v1.Secret, error) {
+	var secret v1.Secret
+	if err := runtime.DecodeInto(codec, data, &secret); err != nil {
+		return nil, err
+	}
+	secret.TypeMeta = metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"}
+	return &secret, nil
+}
+
+// documentError names the failing document if there is more than one.
+func (d *secretDocuments) documentError(i int, err error) error {
+	if len(d.secrets) == 1 {
+		return err
+	}
+	return fmt.Errorf("document %d (%s): %w", i, d.secrets[i].Name, err)
+}
+
+// join combines the encoded documents in the same order and form (list or stream) as they were read.
+func (d *secretDocuments) join(docs [][]byte, outputFormat string) ([]byte, error) {
+	if d.list {
+		list := &v1.List{TypeMeta: metav1.TypeMeta{APIVersion: "v1", Kind: "List"}}
+		for _, doc := range docs {
+			raw, err := yaml.ToJSON(doc)
+			if err != nil {
+				return nil, err
+			}
+			list.Items = append(list.Items, runtime.RawExtension{Raw: raw})
+		}
+		return encodeObject(list, outputFormat)
+	}
+	if len(docs) == 1 {
+		return docs[0], nil
+	}
+
+	var buf bytes.Buffer
+	for _, doc := range docs {
+		if strings.ToLower(outputFormat) == "yaml" && !bytes.HasPrefix(doc, []byte("---")) {
+			buf.WriteString("---\n")
+		}
+		buf.Write(doc)
+		if !bytes.HasSuffix(doc, []byte("\n")) {
+			buf.WriteString("\n")
+		}
+	}
+	return buf.Bytes(), nil
+}
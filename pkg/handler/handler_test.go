@@ -25,7 +25,7 @@ var _ = Describe("Handler ", func() {
 			gin.SetMode(gin.ReleaseMode)
 			recorder = httptest.NewRecorder()
 			c, _ = gin.CreateTestContext(recorder)
-			h = New(helloWorld, nil, &config.Config{})
+			h = New(helloWorld, nil, nil, nil, &config.Config{})
 		})
 		Context("Health", func() {
 			It("should return OK", func() {
@@ -0,0 +1,138 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	ssv1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/typed/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/fake"
+	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
+	k8stesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("Impersonation", func() {
+		var (
+			recorder *httptest.ResponseRecorder
+			c        *gin.Context
+			h        *SecretsHandler
+			users    []store.UserInfo
+			ssClient *ssfake.Clientset
+		)
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Set("user_session", &store.SessionData{UserInfo: store.UserInfo{
+				Username: "alice",
+				Groups:   []string{"team-a"},
+			}})
+			users = nil
+
+			ssClient = ssfake.NewSimpleClientset(
+				&v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "one"}},
+			)
+			userCoreClient := fake.NewSimpleClientset()
+			userCoreClient.PrependReactor("get", "secrets", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				name := action.(k8stesting.GetAction).GetName()
+				return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, name,
+					errors.New(`User "alice" cannot get resource "secrets" in API group "" in the namespace "team-a"`))
+			})
+
+			userMetaClient := newMetadataClient()
+			userMetaClient.PrependReactor("list", "secrets", func(k8stesting.Action) (bool, runtime.Object, error) {
+				return true, nil, apierrors.NewForbidden(schema.GroupResource{Resource: "secrets"}, "", nil)
+			})
+
+			h = NewHandler(
+				fake.NewSimpleClientset().CoreV1(),
+				ssfake.NewSimpleClientset().BitnamiV1alpha1(),
+				newMetadataClient(secretMetadata("team-a", "one")),
+				&config.Config{},
+			)
+			h.Impersonate("", func(user store.UserInfo) (
+				corev1.CoreV1Interface,
+				ssv1alpha1.BitnamiV1alpha1Interface,
+				metadata.Interface,
+				error,
+			) {
+				users = append(users, user)
+				return userCoreClient.CoreV1(), ssClient.BitnamiV1alpha1(), userMetaClient, nil
+			})
+		})
+
+		It("should list the sealed secrets with the clients of the user", func() {
+			c.Request, _ = http.NewRequest("GET", "/api/secrets", nil)
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			// the user is not allowed to list the secrets, so it is unknown if the secret exists
+			Ω(recorder.Body.String()).Should(Equal(
+				`{"secrets":[{"namespace":"team-a","name":"one","status":"pending"}],"total":1}`,
+			))
+			Ω(users).Should(Equal([]store.UserInfo{{Username: "alice", Groups: []string{"team-a"}}}))
+		})
+
+		It("should return the 403 of the api server", func() {
+			c.Request, _ = http.NewRequest("GET", "/api/secret/team-a/one", nil)
+			c.Params = gin.Params{{Key: "namespace", Value: "team-a"}, {Key: "name", Value: "one"}}
+			h.Secret(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"secrets \"one\" is forbidden: User \"alice\" cannot get resource \"secrets\" in API group \"\" in the namespace \"team-a\""}`))
+		})
+
+		It("should be forbidden without session", func() {
+			c.Keys = nil
+			c.Request, _ = http.NewRequest("GET", "/api/secrets", nil)
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"impersonation requires a user session"}`))
+			Ω(users).Should(BeEmpty())
+		})
+
+		It("should impersonate the user of the session", func() {
+			var impersonated http.Header
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				impersonated = r.Header.Clone()
+				w.Header().Set("Content-Type", "application/json")
+				_ = json.NewEncoder(w).Encode(&v1alpha1.SealedSecretList{
+					TypeMeta: metav1.TypeMeta{APIVersion: "bitnami.com/v1alpha1", Kind: "SealedSecretList"},
+				})
+			}))
+			DeferCleanup(server.Close)
+			clientConfig := clientcmd.NewDefaultClientConfig(clientcmdapi.Config{
+				Clusters:       map[string]*clientcmdapi.Cluster{"test": {Server: server.URL}},
+				Contexts:       map[string]*clientcmdapi.Context{"test": {Cluster: "test"}},
+				CurrentContext: "test",
+			}, &clientcmd.ConfigOverrides{})
+
+			clientsFor, err := BuildImpersonatingClients(clientConfig, false)
+			Ω(err).ShouldNot(HaveOccurred())
+			_, ssc, _, err := clientsFor(store.UserInfo{Username: "alice", Groups: []string{"team-a", "team-b"}})
+			Ω(err).ShouldNot(HaveOccurred())
+			_, err = ssc.SealedSecrets("team-a").List(c, metav1.ListOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(impersonated.Get("Impersonate-User")).Should(Equal("alice"))
+			Ω(impersonated.Values("Impersonate-Group")).Should(Equal([]string{"team-a", "team-b"}))
+		})
+	})
+})
@@ -1,30 +1,97 @@
 package handler
 
 import (
+	"errors"
+	"fmt"
 	"net/http"
 
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
 	"github.com/gattma/sealed-secrets-web/pkg/config"
 	"github.com/gattma/sealed-secrets-web/pkg/seal"
 	"github.com/gattma/sealed-secrets-web/pkg/version"
 	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
 type Handler struct {
-	sealer    seal.Sealer
-	indexHTML string
-	filter    *config.FieldFilter
-	cfg       *config.Config
+	sealer     seal.Sealer
+	sealers    map[string]seal.Sealer
+	unsealer   seal.Unsealer
+	namespaces NamespaceChecker
+	policy     *config.Policy
+	indexHTML  string
+	filter     *config.FieldFilter
+	cfg        *config.Config
 }
 
-func New(indexHTML string, sealer seal.Sealer, cfg *config.Config) *Handler {
+func New(
+	indexHTML string,
+	sealers map[string]seal.Sealer,
+	unsealer seal.Unsealer,
+	namespaces NamespaceChecker,
+	cfg *config.Config,
+) *Handler {
+	defaultController, _ := cfg.Controller("")
 	return &Handler{
-		sealer:    sealer,
-		indexHTML: indexHTML,
-		cfg:       cfg,
-		filter:    cfg.FieldFilter,
+		sealer:     sealers[defaultController.Name],
+		sealers:    sealers,
+		unsealer:   unsealer,
+		namespaces: namespaces,
+		policy:     cfg.Policy(),
+		indexHTML:  indexHTML,
+		cfg:        cfg,
+		filter:     cfg.FieldFilter,
 	}
 }
 
+// sealerFor returns the sealer of the controller selected with the 'controller' query parameter.
+// If no controller is selected, the default sealer is returned.
+func (h *Handler) sealerFor(c *gin.Context) (seal.Sealer, error) {
+	name := c.Query("controller")
+	if name == "" {
+		return h.sealer, nil
+	}
+	if s, ok := h.sealers[name]; ok {
+		return s, nil
+	}
+	return nil, fmt.Errorf("unknown controller '%s'", Sanitize(name))
+}
+
+// checkNamespace returns an error if the namespace is not allowed, or the user of the session
+// is not allowed to run the operation in the namespace. The empty namespace stands for all namespaces.
+func (h *Handler) checkNamespace(c *gin.Context, op config.Operation, namespace string) error {
+	if h.namespaces != nil {
+		if err := h.namespaces.CheckNamespace(c, namespace); err != nil {
+			return err
+		}
+	}
+	return authorize(c, h.policy, op, namespace)
+}
+
+// targetNamespace returns the namespace the object is sealed for: all namespaces ("") with the cluster-wide scope,
+// otherwise the namespace of the object or, like kubeseal, the default namespace of the sealer.
+// The default scope lets the scope annotations of the object decide.
+func targetNamespace(sealer seal.Sealer, scope v1alpha1.SealingScope, obj metav1.Object) (string, error) {
+	if scope == v1alpha1.DefaultScope {
+		scope = v1alpha1.SecretScope(obj)
+	}
+	if scope == v1alpha1.ClusterWideScope {
+		return "", nil
+	}
+	if obj.GetNamespace() != "" {
+		return obj.GetNamespace(), nil
+	}
+	return sealer.DefaultNamespace()
+}
+
+// namespaceStatus returns 422 if the namespace of a secret sealed offline is missing, otherwise the forbiddenStatus.
+func namespaceStatus(err error, status int) int {
+	if errors.Is(err, seal.ErrNamespaceRequired) {
+		return http.StatusUnprocessableEntity
+	}
+	return forbiddenStatus(err, status)
+}
+
 func (h *Handler) Index(c *gin.Context) {
 	c.Writer.Header().Set("Content-Type", "text/html; charset=utf-8")
 	c.String(http.StatusOK, h.indexHTML)
@@ -1,13 +1,21 @@
 package handler
 
 import (
+	"bytes"
+	"encoding/json"
 	"errors"
+	"fmt"
 	"log"
 	"net/http"
 
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gattma/sealed-secrets-web/pkg/seal"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	v1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
 )
 
 func (h *Handler) KubeSeal(c *gin.Context) {
@@ -15,21 +23,97 @@ func (h *Handler) KubeSeal(c *gin.Context) {
 	if done {
 		return
 	}
-	log.Printf("Request %s", c.Request.Body)
-	ss, err := h.sealer.Seal(outputFormat, c.Request.Body)
+	sealer, err := h.sealerFor(c)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusBadRequest, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+	scope, err := parseScope(c.Query("scope"))
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusBadRequest, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+	docs, err := readSecrets(scheme.Codecs.UniversalDecoder(), c.Request.Body)
+	if err != nil {
+		log.Printf("Error in %s: %s\n", Sanitize(c.Request.URL.Path), Sanitize(err.Error()))
+		contextNegotiate(c, http.StatusUnprocessableEntity, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+
+	for i, secret := range docs.secrets {
+		namespace, err := targetNamespace(sealer, scope, secret)
+		if err == nil {
+			err = h.checkNamespace(c, config.OperationSeal, namespace)
+		}
+		if err != nil {
+			err = docs.documentError(i, err)
+			log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+			contextNegotiate(c, namespaceStatus(err, http.StatusInternalServerError), gin.Negotiate{
+				Offered: []string{outputContentType},
+				Data:    gin.H{"error": err.Error()},
+			})
+			return
+		}
+		if namespace != "" {
+			secret.Namespace = namespace
+		}
+	}
+
+	sealed := make([][]byte, len(docs.secrets))
+	for i, secret := range docs.secrets {
+		if sealed[i], err = sealSecret(sealer, outputFormat, scope, secret); err != nil {
+			err = docs.documentError(i, err)
+			log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+			contextNegotiate(c, http.StatusInternalServerError, gin.Negotiate{
+				Offered: []string{outputContentType},
+				Data:    gin.H{"error": err.Error()},
+			})
+			return
+		}
+	}
+
+	ss, err := docs.join(sealed, outputFormat)
 	if err != nil {
 		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
 		contextNegotiate(c, http.StatusInternalServerError, gin.Negotiate{
 			Offered: []string{outputContentType},
 			Data:    gin.H{"error": err.Error()},
 		})
-		c.Data(http.StatusInternalServerError, outputContentType, ss)
 		return
 	}
 
 	c.Data(http.StatusOK, outputContentType, ss)
 }
 
+func sealSecret(sealer seal.Sealer, outputFormat string, scope v1alpha1.SealingScope, secret *v1.Secret) ([]byte, error) {
+	b, err := json.Marshal(secret)
+	if err != nil {
+		return nil, err
+	}
+	return sealer.Seal(outputFormat, scope, bytes.NewReader(b))
+}
+
+// parseScope parses the sealing scope. An empty scope results in the default scope,
+// which lets the scope annotations of the input secret decide.
+func parseScope(value string) (v1alpha1.SealingScope, error) {
+	scope := v1alpha1.DefaultScope
+	if err := scope.Set(value); err != nil {
+		return scope, fmt.Errorf("invalid scope '%s': %w", value, err)
+	}
+	return scope, nil
+}
+
 // fox for gin 1.10 incomplete yaml handling https://github.com/gin-gonic/gin/issues/3965
 func contextNegotiate(c *gin.Context, code int, config gin.Negotiate) {
 	switch c.NegotiateFormat(config.Offered...) {
@@ -45,8 +129,7 @@ func contextNegotiate(c *gin.Context, code int, config gin.Negotiate) {
 		data := config.Data
 		c.XML(code, data)
 
-	case binding.MIMEYAML:
-	case binding.MIMEYAML2:
+	case binding.MIMEYAML, binding.MIMEYAML2:
 		data := config.Data
 		c.YAML(code, data)
 
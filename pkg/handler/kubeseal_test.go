@@ -2,15 +2,20 @@ package handler
 
 import (
 	"bytes"
+	"encoding/json"
 	"errors"
+	"io"
 	"net/http"
 	"net/http/httptest"
 
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
 	"github.com/gattma/sealed-secrets-web/pkg/mocks/seal"
+	ssw "github.com/gattma/sealed-secrets-web/pkg/seal"
 	"github.com/gin-gonic/gin"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
 )
 
 var _ = Describe("Handler ", func() {
@@ -28,6 +33,7 @@ var _ = Describe("Handler ", func() {
 			c, _ = gin.CreateTestContext(recorder)
 			mock = gomock.NewController(GinkgoT())
 			sealer = seal.NewMockSealer(mock)
+			sealer.EXPECT().DefaultNamespace().Return("default", nil).AnyTimes()
 			h = &Handler{
 				sealer: sealer,
 			}
@@ -38,7 +44,7 @@ var _ = Describe("Handler ", func() {
 			c.Request.Header.Set("Content-Type", "application/json")
 			c.Request.Header.Set("Accept", "application/json")
 
-			sealer.EXPECT().Seal("json", gomock.Any()).Return([]byte(sealAsJSON), nil)
+			sealer.EXPECT().Seal("json", v1alpha1.DefaultScope, gomock.Any()).Return([]byte(sealAsJSON), nil)
 
 			h.KubeSeal(c)
 
@@ -52,7 +58,7 @@ var _ = Describe("Handler ", func() {
 			c.Request.Header.Set("Content-Type", "application/yaml")
 			c.Request.Header.Set("Accept", "application/yaml")
 
-			sealer.EXPECT().Seal("yaml", gomock.Any()).Return([]byte(sealedAsYAML), nil)
+			sealer.EXPECT().Seal("yaml", v1alpha1.DefaultScope, gomock.Any()).Return([]byte(sealedAsYAML), nil)
 
 			h.KubeSeal(c)
 
@@ -66,7 +72,7 @@ var _ = Describe("Handler ", func() {
 			c.Request.Header.Set("Content-Type", "application/yaml")
 			c.Request.Header.Set("Accept", "application/yaml")
 
-			sealer.EXPECT().Seal(gomock.Any(), gomock.Any()).Return(nil, errors.New("error sealing"))
+			sealer.EXPECT().Seal(gomock.Any(), gomock.Any(), gomock.Any()).Return(nil, errors.New("error sealing"))
 
 			h.KubeSeal(c)
 
@@ -74,5 +80,193 @@ var _ = Describe("Handler ", func() {
 			Ω(recorder.Body.String()).Should(Equal("error: error sealing\n"))
 			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/yaml; charset=utf-8"))
 		})
+
+		It("should require a namespace when sealing offline", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/kubeseal", bytes.NewReader([]byte(stringDataAsJSON)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/json")
+
+			offline := seal.NewMockSealer(mock)
+			offline.EXPECT().DefaultNamespace().Return("", ssw.ErrNamespaceRequired)
+			h.sealer = offline
+
+			h.KubeSeal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusUnprocessableEntity))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"namespace is required when sealing offline"}`))
+		})
+
+		It("should kubeseal multi document yaml in the same order", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/kubeseal", bytes.NewReader([]byte(multiDocAsYAML)))
+			c.Request.Header.Set("Content-Type", "application/yaml")
+			c.Request.Header.Set("Accept", "application/yaml")
+
+			gomock.InOrder(
+				sealer.EXPECT().Seal("yaml", v1alpha1.DefaultScope, secretNamed("first")).
+					Return([]byte("---\nkind: SealedSecret\nmetadata:\n  name: first\n"), nil),
+				sealer.EXPECT().Seal("yaml", v1alpha1.DefaultScope, secretNamed("second")).
+					Return([]byte("---\nkind: SealedSecret\nmetadata:\n  name: second\n"), nil),
+			)
+
+			h.KubeSeal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(`---
+kind: SealedSecret
+metadata:
+  name: first
+---
+kind: SealedSecret
+metadata:
+  name: second
+`))
+		})
+
+		It("should kubeseal a list and output a list", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/kubeseal", bytes.NewReader([]byte(listAsJSON)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/json")
+
+			gomock.InOrder(
+				sealer.EXPECT().Seal("json", v1alpha1.DefaultScope, secretNamed("first")).
+					Return([]byte(`{"kind": "SealedSecret", "metadata": {"name": "first"}}`), nil),
+				sealer.EXPECT().Seal("json", v1alpha1.DefaultScope, secretNamed("second")).
+					Return([]byte(`{"kind": "SealedSecret", "metadata": {"name": "second"}}`), nil),
+			)
+
+			h.KubeSeal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(`{
+  "kind": "List",
+  "apiVersion": "v1",
+  "metadata": {},
+  "items": [
+    {
+      "kind": "SealedSecret",
+      "metadata": {
+        "name": "first"
+      }
+    },
+    {
+      "kind": "SealedSecret",
+      "metadata": {
+        "name": "second"
+      }
+    }
+  ]
+}`))
+		})
+
+		It("should name the failing document", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/kubeseal", bytes.NewReader([]byte(multiDocAsYAML)))
+			c.Request.Header.Set("Content-Type", "application/yaml")
+			c.Request.Header.Set("Accept", "application/json")
+
+			sealer.EXPECT().Seal("json", v1alpha1.DefaultScope, secretNamed("first")).Return([]byte(sealAsJSON), nil)
+			sealer.EXPECT().Seal("json", v1alpha1.DefaultScope, secretNamed("second")).
+				Return(nil, errors.New("error sealing"))
+
+			h.KubeSeal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusInternalServerError))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"document 1 (second): error sealing"}`))
+		})
+
+		It("should return an error if the input can not be read", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/kubeseal", bytes.NewReader([]byte(invalidMultiDocAsYAML)))
+			c.Request.Header.Set("Content-Type", "application/yaml")
+			c.Request.Header.Set("Accept", "application/json")
+
+			h.KubeSeal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusUnprocessableEntity))
+			Ω(recorder.Body.String()).Should(ContainSubstring(`{"error":"document 1:`))
+		})
+
+		DescribeTable("should kubeseal with the requested scope",
+			func(query string, scope v1alpha1.SealingScope) {
+				c.Request, _ = http.NewRequest(
+					"POST",
+					"/v1/kubeseal?scope="+query,
+					bytes.NewReader([]byte(stringDataAsJSON)),
+				)
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				sealer.EXPECT().Seal("json", scope, gomock.Any()).Return([]byte(sealAsJSON), nil)
+
+				h.KubeSeal(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(recorder.Body.String()).Should(Equal(sealAsJSON))
+			},
+			Entry("default", "", v1alpha1.DefaultScope),
+			Entry("strict", "strict", v1alpha1.StrictScope),
+			Entry("namespace-wide", "namespace-wide", v1alpha1.NamespaceWideScope),
+			Entry("cluster-wide", "cluster-wide", v1alpha1.ClusterWideScope),
+		)
+
+		It("should kubeseal with the selected controller", func() {
+			other := seal.NewMockSealer(mock)
+			other.EXPECT().DefaultNamespace().Return("default", nil).AnyTimes()
+			h.sealers = map[string]ssw.Sealer{"other": other}
+			c.Request, _ = http.NewRequest(
+				"POST",
+				"/v1/kubeseal?controller=other",
+				bytes.NewReader([]byte(stringDataAsJSON)),
+			)
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/json")
+
+			other.EXPECT().Seal("json", v1alpha1.DefaultScope, gomock.Any()).Return([]byte(sealAsJSON), nil)
+
+			h.KubeSeal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(sealAsJSON))
+		})
+
+		It("should return an error if the controller is unknown", func() {
+			c.Request, _ = http.NewRequest(
+				"POST",
+				"/v1/kubeseal?controller=foo",
+				bytes.NewReader([]byte(stringDataAsJSON)),
+			)
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/json")
+
+			h.KubeSeal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"unknown controller 'foo'"}`))
+		})
+
+		It("should return an error if the scope is invalid", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/kubeseal?scope=foo", bytes.NewReader([]byte(stringDataAsJSON)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/json")
+
+			h.KubeSeal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+			Ω(
+				recorder.Body.String(),
+			).Should(Equal(`{"error":"invalid scope 'foo': must be one of: strict, namespace-wide, cluster-wide"}`))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/json; charset=utf-8"))
+		})
 	})
 })
+
+// secretNamed matches a secret input by its name.
+func secretNamed(name string) gomock.Matcher {
+	return gomock.Cond(func(x any) bool {
+		r, ok := x.(io.ReadSeeker)
+		if !ok {
+			return false
+		}
+		defer func() { _, _ = r.Seek(0, io.SeekStart) }()
+		var secret v1.Secret
+		return json.NewDecoder(r).Decode(&secret) == nil && secret.Name == name
+	})
+}
@@ -0,0 +1,92 @@
+package handler
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gattma/sealed-secrets-web/pkg/seal"
+	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/yaml"
+)
+
+func (h *Handler) Merge(c *gin.Context) {
+	outputContentType, outputFormat, done := NegotiateFormat(c)
+	if done {
+		return
+	}
+	sealer, err := h.sealerFor(c)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusBadRequest, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+	data := &seal.Merge{}
+	if err := c.ShouldBindJSON(&data); err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	target, err := objectMetadata(data.SealedSecret)
+	if err != nil {
+		err = fmt.Errorf("invalid sealed secret: %w", err)
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusBadRequest, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+	// like kubeseal, the secret is merged into the namespace of the sealed secret or its own namespace
+	if target.Namespace == "" {
+		target.Namespace = objectNamespace(data.Secret)
+	}
+	namespace, err := targetNamespace(sealer, v1alpha1.DefaultScope, target)
+	if err == nil {
+		err = h.checkNamespace(c, config.OperationSeal, namespace)
+	}
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, namespaceStatus(err, http.StatusInternalServerError), gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+	ss, err := sealer.Merge(outputFormat, *data)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusInternalServerError, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, outputContentType, ss)
+}
+
+// objectNamespace returns the namespace of the yaml or json object, or an empty string if it can't be decoded.
+func objectNamespace(obj string) string {
+	meta, err := objectMetadata(obj)
+	if err != nil {
+		return ""
+	}
+	return meta.Namespace
+}
+
+// objectMetadata decodes the metadata of the yaml or json object.
+func objectMetadata(obj string) (*metav1.PartialObjectMetadata, error) {
+	var meta metav1.PartialObjectMetadata
+	if err := yaml.NewYAMLOrJSONDecoder(strings.NewReader(obj), 4096).Decode(&meta); err != nil {
+		return nil, err
+	}
+	return &meta, nil
+}
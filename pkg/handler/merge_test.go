@@ -0,0 +1,104 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gattma/sealed-secrets-web/pkg/mocks/seal"
+	ssw "github.com/gattma/sealed-secrets-web/pkg/seal"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("Merge", func() {
+		var (
+			recorder *httptest.ResponseRecorder
+			c        *gin.Context
+			mock     *gomock.Controller
+			sealer   *seal.MockSealer
+			h        *Handler
+		)
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			mock = gomock.NewController(GinkgoT())
+			sealer = seal.NewMockSealer(mock)
+			sealer.EXPECT().DefaultNamespace().Return("default", nil).AnyTimes()
+			h = &Handler{
+				sealer: sealer,
+			}
+		})
+
+		It("should merge the secret into the sealed secret and output as yaml", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/merge", bytes.NewReader([]byte(mergeData)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/yaml")
+
+			sealer.EXPECT().Merge("yaml", gomock.Any()).Return([]byte(sealedAsYAML), nil)
+
+			h.Merge(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(sealedAsYAML))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/yaml"))
+		})
+
+		It("should merge the secret into the sealed secret and output as json", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/merge", bytes.NewReader([]byte(mergeData)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/json")
+
+			sealer.EXPECT().Merge("json", sealerMerge()).Return([]byte(sealAsJSON), nil)
+
+			h.Merge(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(sealAsJSON))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/json"))
+		})
+
+		It("should return an error if body can not be parsed as json", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/merge", bytes.NewReader([]byte("foo")))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/json")
+
+			h.Merge(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusUnprocessableEntity))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/json; charset=utf-8"))
+		})
+
+		It("should return an error if merge is not successful", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/merge", bytes.NewReader([]byte(mergeData)))
+			c.Request.Header.Set("Content-Type", "application/json")
+			c.Request.Header.Set("Accept", "application/json")
+
+			sealer.EXPECT().Merge(gomock.Any(), gomock.Any()).Return(nil, errors.New("error merging"))
+
+			h.Merge(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusInternalServerError))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"error merging"}`))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/json; charset=utf-8"))
+		})
+	})
+})
+
+func sealerMerge() ssw.Merge {
+	return ssw.Merge{
+		SealedSecret: sealAsJSON,
+		Secret:       "apiVersion: v1\nkind: Secret\n",
+	}
+}
+
+const mergeData = `{
+  "sealedSecret": "{\"apiVersion\": \"bitnami.com/v1alpha1\"}",
+  "secret": "apiVersion: v1\nkind: Secret\n"
+}
+`
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"sort"
+
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var errNamespaceNotAllowed = errors.New("not allowed")
+
+// NamespaceChecker checks if the namespace can be used with the controller selected by the request.
+type NamespaceChecker interface {
+	CheckNamespace(c *gin.Context, namespace string) error
+}
+
+var _ NamespaceChecker = &SecretsHandler{}
+
+// CheckNamespace returns an error if the namespace is not allowed for the selected controller.
+func (h *SecretsHandler) CheckNamespace(c *gin.Context, namespace string) error {
+	ch, err := h.forController(c)
+	if err != nil {
+		return err
+	}
+	return ch.namespaceAllowed(c, namespace)
+}
+
+// namespaceAllowed returns an error if the namespace does not match the namespace filter or selector.
+// The empty namespace stands for all namespaces (cluster-wide secrets), it is only allowed if all namespaces are.
+func (h *SecretsHandler) namespaceAllowed(ctx context.Context, namespace string) error {
+	if namespace == "" {
+		if h.namespaceFilter.MatchesAll() && h.namespaceSelector == nil {
+			return nil
+		}
+		return fmt.Errorf("cluster-wide secrets are %w, only some namespaces are allowed", errNamespaceNotAllowed)
+	}
+	if !h.namespaceFilter.Matches(namespace) {
+		return fmt.Errorf("namespace '%s' is %w", namespace, errNamespaceNotAllowed)
+	}
+	if h.namespaceSelector == nil {
+		return nil
+	}
+	if h.namespaceClient == nil {
+		return errors.New("the namespace selector requires access to the cluster")
+	}
+	ns, err := h.namespaceClient.Namespaces().Get(ctx, namespace, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		return fmt.Errorf("namespace '%s' is %w", namespace, errNamespaceNotAllowed)
+	}
+	if err != nil {
+		return err
+	}
+	if !h.namespaceSelector.Matches(labels.Set(ns.Labels)) {
+		return fmt.Errorf("namespace '%s' is %w", namespace, errNamespaceNotAllowed)
+	}
+	return nil
+}
+
+// forbiddenStatus returns 403 if the namespace is not allowed or the api server denied the request,
+// otherwise the given status.
+func forbiddenStatus(err error, status int) int {
+	if errors.Is(err, errNamespaceNotAllowed) || errors.Is(err, errNoSession) || apierrors.IsForbidden(err) {
+		return http.StatusForbidden
+	}
+	return status
+}
+
+// unrestricted returns true if all namespaces are allowed.
+func (h *SecretsHandler) unrestricted() bool {
+	return h.namespaceFilter.Empty() && h.namespaceSelector == nil
+}
+
+// namespaces returns the namespaces to list the secrets of. An empty namespace lists all allowed namespaces.
+// Unless the allowed namespaces are given by name, they are resolved by listing the namespaces.
+func (h *SecretsHandler) namespaces(ctx context.Context, namespace string) ([]string, error) {
+	if namespace != "" {
+		if err := h.namespaceAllowed(ctx, namespace); err != nil {
+			return nil, err
+		}
+		return []string{namespace}, nil
+	}
+	if h.unrestricted() {
+		return []string{""}, nil
+	}
+	if literals, ok := h.namespaceFilter.Literals(); ok && h.namespaceSelector == nil {
+		return literals, nil
+	}
+
+	opts := metav1.ListOptions{}
+	if h.namespaceSelector != nil {
+		opts.LabelSelector = h.namespaceSelector.String()
+	}
+	list, err := h.namespaceClient.Namespaces().List(ctx, opts)
+	if err != nil {
+		return nil, err
+	}
+	namespaces := []string{}
+	for _, ns := range list.Items {
+		if h.namespaceFilter.Matches(ns.Name) {
+			namespaces = append(namespaces, ns.Name)
+		}
+	}
+	sort.Strings(namespaces)
+	return namespaces, nil
+}
+
+// restrictNamespaces returns the namespaces matching the predicate. All namespaces ("") are resolved by listing them.
+func (h *SecretsHandler) restrictNamespaces(
+	ctx context.Context,
+	namespaces []string,
+	allowed func(namespace string) bool,
+) ([]string, error) {
+	if slices.Equal(namespaces, []string{""}) {
+		list, err := h.namespaceClient.Namespaces().List(ctx, metav1.ListOptions{})
+		if err != nil {
+			return nil, err
+		}
+		namespaces = make([]string, 0, len(list.Items))
+		for _, ns := range list.Items {
+			namespaces = append(namespaces, ns.Name)
+		}
+		sort.Strings(namespaces)
+	}
+	return slices.DeleteFunc(slices.Clone(namespaces), func(ns string) bool {
+		return !allowed(ns)
+	}), nil
+}
+
+// watchNamespaces returns the namespaces to be watched. If the allowed namespaces are not given by name,
+// all namespaces are watched and filtered is true.
+func (h *SecretsHandler) watchNamespaces() (namespaces []string, filtered bool) {
+	if literals, ok := h.namespaceFilter.Literals(); ok && h.namespaceSelector == nil {
+		return literals, false
+	}
+	return []string{""}, !h.unrestricted()
+}
@@ -0,0 +1,288 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gattma/sealed-secrets-web/pkg/mocks/seal"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("Namespaces", func() {
+		var (
+			recorder *httptest.ResponseRecorder
+			c        *gin.Context
+			sh       *SecretsHandler
+		)
+		namespace := func(name string, labels map[string]string) *v1.Namespace {
+			return &v1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels}}
+		}
+		sealedSecret := func(ns, name string) *v1alpha1.SealedSecret {
+			return &v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: ns, Name: name}}
+		}
+		newHandler := func(cfg *config.Config) *SecretsHandler {
+			coreClient := fake.NewSimpleClientset(
+				namespace("team-a", map[string]string{"team": "a"}),
+				namespace("team-b", map[string]string{"team": "b"}),
+				namespace("team-prod", map[string]string{"team": "a"}),
+				namespace("kube-system", nil),
+			)
+			ssClient := ssfake.NewSimpleClientset(
+				sealedSecret("team-a", "one"),
+				sealedSecret("team-b", "two"),
+				sealedSecret("team-prod", "three"),
+				sealedSecret("kube-system", "four"),
+			)
+			return NewHandler(coreClient.CoreV1(), ssClient.BitnamiV1alpha1(), newMetadataClient(), cfg)
+		}
+		list := func(query string) []string {
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Request, _ = http.NewRequest("GET", "/api/secrets"+query, nil)
+			sh.AllSecrets(c)
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			var result SecretList
+			Ω(json.Unmarshal(recorder.Body.Bytes(), &result)).Should(Succeed())
+			var names []string
+			for _, s := range result.Secrets {
+				names = append(names, s.Namespace+"/"+s.Name)
+			}
+			return names
+		}
+
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			c.Request, _ = http.NewRequest("GET", "/", nil)
+		})
+
+		It("should list the namespaces matching the patterns", func() {
+			sh = newHandler(&config.Config{
+				IncludeNamespaces: []string{"team-*"},
+				ExcludeNamespaces: []string{"/.*-prod/"},
+			})
+
+			Ω(list("")).Should(Equal([]string{"team-a/one", "team-b/two"}))
+		})
+
+		It("should list the namespaces matching the selector", func() {
+			sh = newHandler(&config.Config{NamespaceSelector: "team=a"})
+
+			Ω(list("")).Should(Equal([]string{"team-a/one", "team-prod/three"}))
+		})
+
+		It("should combine the patterns and the selector", func() {
+			sh = newHandler(&config.Config{NamespaceSelector: "team=a", ExcludeNamespaces: []string{"*-prod"}})
+
+			Ω(list("")).Should(Equal([]string{"team-a/one"}))
+		})
+
+		It("should check the namespace against the selector", func() {
+			sh = newHandler(&config.Config{NamespaceSelector: "team=a"})
+
+			Ω(sh.CheckNamespace(c, "team-a")).Should(Succeed())
+			Ω(sh.CheckNamespace(c, "team-b")).Should(MatchError("namespace 'team-b' is not allowed"))
+			Ω(sh.CheckNamespace(c, "missing")).Should(MatchError("namespace 'missing' is not allowed"))
+		})
+
+		It("should not return secrets of namespaces not matching the selector", func() {
+			sh = newHandler(&config.Config{NamespaceSelector: "team=a"})
+
+			c.Request, _ = http.NewRequest("GET", "/api/secret/team-b/two", nil)
+			c.Params = gin.Params{{Key: "namespace", Value: "team-b"}, {Key: "name", Value: "two"}}
+			sh.Secret(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"namespace 'team-b' is not allowed"}`))
+		})
+
+		Context("sealing", func() {
+			var (
+				mock   *gomock.Controller
+				sealer *seal.MockSealer
+				h      *Handler
+			)
+			BeforeEach(func() {
+				mock = gomock.NewController(GinkgoT())
+				sealer = seal.NewMockSealer(mock)
+				sh = newHandler(&config.Config{IncludeNamespaces: []string{"team-*"}})
+				h = &Handler{sealer: sealer, namespaces: sh}
+			})
+
+			It("should not kubeseal secrets of namespaces that are not allowed", func() {
+				secret := `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo","namespace":"kube-system"}}`
+				c.Request, _ = http.NewRequest("POST", "/v1/kubeseal", bytes.NewReader([]byte(secret)))
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				h.KubeSeal(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(ContainSubstring("namespace 'kube-system' is not allowed"))
+			})
+
+			It("should not seal raw values of namespaces that are not allowed", func() {
+				data := `{"name":"foo","namespace":"kube-system","value":"bar"}`
+				c.Request, _ = http.NewRequest("POST", "/v1/raw", bytes.NewReader([]byte(data)))
+				c.Request.Header.Set("Content-Type", "application/json")
+
+				h.Raw(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"namespace 'kube-system' is not allowed"}`))
+			})
+
+			It("should seal raw values of allowed namespaces", func() {
+				data := `{"name":"foo","namespace":"team-a","value":"bar"}`
+				c.Request, _ = http.NewRequest("POST", "/v1/raw", bytes.NewReader([]byte(data)))
+				c.Request.Header.Set("Content-Type", "application/json")
+
+				sealer.EXPECT().Raw(gomock.Any()).Return([]byte("foo"), nil)
+
+				h.Raw(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+			})
+
+			It("should not merge into sealed secrets of namespaces that are not allowed", func() {
+				data := `{"sealedSecret":"metadata:\n  namespace: kube-system\n","secret":"kind: Secret\n"}`
+				c.Request, _ = http.NewRequest("POST", "/v1/merge", bytes.NewReader([]byte(data)))
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				h.Merge(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"namespace 'kube-system' is not allowed"}`))
+			})
+
+			It("should check the default namespace of secrets without a namespace", func() {
+				secret := `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo"}}`
+				c.Request, _ = http.NewRequest("POST", "/v1/kubeseal", bytes.NewReader([]byte(secret)))
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				sealer.EXPECT().DefaultNamespace().Return("kube-system", nil)
+
+				h.KubeSeal(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(ContainSubstring("namespace 'kube-system' is not allowed"))
+			})
+
+			It("should kubeseal secrets without a namespace for the checked default namespace", func() {
+				secret := `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo"}}`
+				c.Request, _ = http.NewRequest("POST", "/v1/kubeseal", bytes.NewReader([]byte(secret)))
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				sealer.EXPECT().DefaultNamespace().Return("team-a", nil)
+				sealer.EXPECT().Seal("json", v1alpha1.DefaultScope, gomock.Any()).
+					DoAndReturn(func(_ string, _ v1alpha1.SealingScope, r io.Reader) ([]byte, error) {
+						b, err := io.ReadAll(r)
+						Ω(err).ShouldNot(HaveOccurred())
+						Ω(string(b)).Should(ContainSubstring(`"namespace":"team-a"`))
+						return []byte("{}"), nil
+					})
+
+				h.KubeSeal(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+			})
+
+			DescribeTable("should not kubeseal cluster-wide secrets if not all namespaces are allowed",
+				func(query, secret string) {
+					c.Request, _ = http.NewRequest("POST", "/v1/kubeseal"+query, bytes.NewReader([]byte(secret)))
+					c.Request.Header.Set("Content-Type", "application/json")
+					c.Request.Header.Set("Accept", "application/json")
+
+					h.KubeSeal(c)
+
+					Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+					Ω(recorder.Body.String()).Should(ContainSubstring("cluster-wide secrets are not allowed"))
+				},
+				Entry("requested scope", "?scope=cluster-wide",
+					`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo","namespace":"team-a"}}`),
+				Entry("scope annotation", "",
+					`{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo","namespace":"team-a",`+
+						`"annotations":{"sealedsecrets.bitnami.com/cluster-wide":"true"}}}`),
+			)
+
+			It("should kubeseal cluster-wide secrets if all namespaces are allowed", func() {
+				sh = newHandler(&config.Config{IncludeNamespaces: []string{"*"}})
+				h.namespaces = sh
+				secret := `{"apiVersion":"v1","kind":"Secret","metadata":{"name":"foo"}}`
+				c.Request, _ = http.NewRequest("POST", "/v1/kubeseal?scope=cluster-wide", bytes.NewReader([]byte(secret)))
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				sealer.EXPECT().Seal("json", v1alpha1.ClusterWideScope, gomock.Any()).Return([]byte("{}"), nil)
+
+				h.KubeSeal(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+			})
+
+			It("should not seal cluster-wide raw values if not all namespaces are allowed", func() {
+				data := `{"name":"foo","namespace":"team-a","value":"bar","scope":"cluster-wide"}`
+				c.Request, _ = http.NewRequest("POST", "/v1/raw", bytes.NewReader([]byte(data)))
+				c.Request.Header.Set("Content-Type", "application/json")
+
+				h.Raw(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(ContainSubstring("cluster-wide secrets are not allowed"))
+			})
+
+			It("should not merge into sealed secrets that can't be decoded", func() {
+				data := `{"sealedSecret":"metadata: [\n","secret":"kind: Secret\n"}`
+				c.Request, _ = http.NewRequest("POST", "/v1/merge", bytes.NewReader([]byte(data)))
+				c.Request.Header.Set("Content-Type", "application/json")
+				c.Request.Header.Set("Accept", "application/json")
+
+				h.Merge(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+				Ω(recorder.Body.String()).Should(ContainSubstring("invalid sealed secret"))
+			})
+
+			It("should check the namespaces of all validated documents", func() {
+				h.cfg = &config.Config{}
+				docs := "metadata:\n  name: foo\n  namespace: team-a\n---\nmetadata:\n  name: bar\n  namespace: kube-system\n"
+				c.Request, _ = http.NewRequest("POST", "/v1/validate", bytes.NewReader([]byte(docs)))
+				c.Request.Header.Set("Content-Type", "application/yaml")
+
+				h.Validate(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal("document 1 (bar): namespace 'kube-system' is not allowed"))
+			})
+
+			It("should not re-encrypt sealed secrets of namespaces that are not allowed", func() {
+				h.cfg = &config.Config{}
+				c.Request, _ = http.NewRequest("POST", "/v1/reencrypt",
+					bytes.NewReader([]byte("metadata:\n  name: foo\n  namespace: kube-system\n")))
+				c.Request.Header.Set("Accept", "application/json")
+
+				h.ReEncrypt(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"namespace 'kube-system' is not allowed"}`))
+			})
+		})
+	})
+})
@@ -4,6 +4,8 @@ import (
 	"log"
 	"net/http"
 
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
 	"github.com/gattma/sealed-secrets-web/pkg/seal"
 	"github.com/gin-gonic/gin"
 )
@@ -13,13 +15,30 @@ type secret struct {
 }
 
 func (h *Handler) Raw(c *gin.Context) {
+	sealer, err := h.sealerFor(c)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
 	data := &seal.Raw{}
 	if err := c.ShouldBindJSON(&data); err != nil {
 		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
 		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
 		return
 	}
-	r, err := h.sealer.Raw(*data)
+	// an invalid scope falls back to the default scope like in the sealer
+	scope, _ := parseScope(data.Scope)
+	namespace := data.Namespace
+	if scope == v1alpha1.ClusterWideScope {
+		namespace = ""
+	}
+	if err := h.checkNamespace(c, config.OperationSeal, namespace); err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	r, err := sealer.Raw(*data)
 	if err != nil {
 		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+// ReEncrypt re-encrypts the posted sealed secrets with the latest key of the controller.
+func (h *Handler) ReEncrypt(c *gin.Context) {
+	outputContentType, outputFormat, done := NegotiateFormat(c)
+	if done {
+		return
+	}
+	sealer, err := h.sealerFor(c)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusBadRequest, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+	if configError := h.controllerServiceRequired(c, "re-encrypt"); configError != nil {
+		contextNegotiate(c, http.StatusConflict, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": configError.Error()},
+		})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusBadRequest, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+	objects, err := readObjectsMetadata(scheme.Codecs.UniversalDecoder(), bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusBadRequest, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+	if err := h.checkSealedNamespaces(c, sealer, config.OperationSeal, objects); err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, namespaceStatus(err, http.StatusInternalServerError), gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+
+	ss, err := sealer.Rotate(c, outputFormat, bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		contextNegotiate(c, http.StatusBadRequest, gin.Negotiate{
+			Offered: []string{outputContentType},
+			Data:    gin.H{"error": err.Error()},
+		})
+		return
+	}
+
+	c.Data(http.StatusOK, outputContentType, ss)
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gattma/sealed-secrets-web/pkg/mocks/seal"
+	ssw "github.com/gattma/sealed-secrets-web/pkg/seal"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("ReEncrypt", func() {
+		var (
+			recorder *httptest.ResponseRecorder
+			c        *gin.Context
+			mock     *gomock.Controller
+			sealer   *seal.MockSealer
+			h        *Handler
+			cfg      *config.Config
+		)
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			mock = gomock.NewController(GinkgoT())
+			sealer = seal.NewMockSealer(mock)
+			sealer.EXPECT().DefaultNamespace().Return("default", nil).AnyTimes()
+			cfg = &config.Config{}
+			h = &Handler{
+				sealer: sealer,
+				cfg:    cfg,
+			}
+		})
+
+		It("should return the re-encrypted sealed secret", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/reencrypt", bytes.NewReader([]byte(sealAsJSON)))
+			c.Request.Header.Set("Accept", "application/yaml")
+
+			sealer.EXPECT().Rotate(gomock.Any(), "yaml", gomock.Any()).Return([]byte("---\nkind: SealedSecret\n"), nil)
+
+			h.ReEncrypt(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal("---\nkind: SealedSecret\n"))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/yaml"))
+		})
+
+		It("should return an error if re-encrypt fails", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/reencrypt", bytes.NewReader([]byte(sealAsJSON)))
+			c.Request.Header.Set("Accept", "application/json")
+
+			sealer.EXPECT().Rotate(gomock.Any(), "json", gomock.Any()).Return(nil, errors.New("unable to rotate secret"))
+
+			h.ReEncrypt(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"unable to rotate secret"}`))
+		})
+
+		It("should return an error if the selected controller is offline", func() {
+			cfg.Controllers = []config.Controller{
+				{Name: "cluster"},
+				{Name: "offline", SealedSecrets: config.SealedSecrets{CertFile: "cert.pem"}},
+			}
+			h.sealers = map[string]ssw.Sealer{"cluster": sealer, "offline": sealer}
+			c.Request, _ = http.NewRequest("POST", "/v1/reencrypt?controller=offline", bytes.NewReader([]byte(sealAsJSON)))
+			c.Request.Header.Set("Accept", "application/json")
+
+			h.ReEncrypt(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusConflict))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"re-encrypt can't be used in offline mode (Cert file: cert.pem)"}`))
+		})
+	})
+})
@@ -2,21 +2,34 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
+	"maps"
 	"net/http"
+	"slices"
 	"sort"
 	"strings"
+	"time"
 
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
 	ssClient "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/typed/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
 	"github.com/gattma/sealed-secrets-web/pkg/config"
 	"github.com/gin-gonic/gin"
 	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/apimachinery/pkg/runtime/schema"
+	jsonserializer "k8s.io/apimachinery/pkg/runtime/serializer/json"
 	"k8s.io/client-go/kubernetes/scheme"
 	corev1 "k8s.io/client-go/kubernetes/typed/core/v1"
+	"k8s.io/client-go/metadata"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 )
 
@@ -24,98 +37,537 @@ import (
 func BuildClients(
 	clientConfig clientcmd.ClientConfig,
 	disableLoadSecrets bool,
-) (corev1.CoreV1Interface, ssClient.BitnamiV1alpha1Interface, error) {
+) (corev1.CoreV1Interface, ssClient.BitnamiV1alpha1Interface, metadata.Interface, error) {
 	if disableLoadSecrets {
-		return nil, nil, nil
+		return nil, nil, nil, nil
 	}
 	conf, err := clientConfig.ClientConfig()
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
+	return buildClients(conf)
+}
 
+func buildClients(conf *rest.Config) (corev1.CoreV1Interface, ssClient.BitnamiV1alpha1Interface, metadata.Interface, error) {
 	restClient, err := corev1.NewForConfig(conf)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
 	}
 
 	ssCl, err := ssClient.NewForConfig(conf)
 	if err != nil {
-		return nil, nil, err
+		return nil, nil, nil, err
+	}
+
+	metadataClient, err := metadata.NewForConfig(conf)
+	if err != nil {
+		return nil, nil, nil, err
 	}
 
-	return restClient, ssCl, nil
+	return restClient, ssCl, metadataClient, nil
+}
+
+// ClientsFor builds the clients used for the requests of the user.
+type ClientsFor func(user store.UserInfo) (
+	corev1.CoreV1Interface,
+	ssClient.BitnamiV1alpha1Interface,
+	metadata.Interface,
+	error,
+)
+
+// BuildImpersonatingClients returns a function building clients which impersonate the user and the groups
+// of the session, so the permissions of the user are checked by the api server.
+func BuildImpersonatingClients(clientConfig clientcmd.ClientConfig, disableLoadSecrets bool) (ClientsFor, error) {
+	if disableLoadSecrets {
+		return nil, nil
+	}
+	conf, err := clientConfig.ClientConfig()
+	if err != nil {
+		return nil, err
+	}
+	return func(user store.UserInfo) (
+		corev1.CoreV1Interface,
+		ssClient.BitnamiV1alpha1Interface,
+		metadata.Interface,
+		error,
+	) {
+		userConf := rest.CopyConfig(conf)
+		userConf.Impersonate = rest.ImpersonationConfig{UserName: user.Username, Groups: user.Groups}
+		return buildClients(userConf)
+	}, nil
 }
 
+// errNoSession is returned if the clients of the user can't be built without session.
+var errNoSession = errors.New("impersonation requires a user session")
+
 // SecretsHandler handles our secrets operations.
 type SecretsHandler struct {
 	coreClient         corev1.CoreV1Interface
 	ssClient           ssClient.BitnamiV1alpha1Interface
+	metadataClient     metadata.Interface
+	namespaceClient    corev1.NamespacesGetter
+	clientsFor         ClientsFor
+	reviewer           *accessReviewer
 	disableLoadSecrets bool
-	includeNamespaces  map[string]bool
+	namespaceFilter    *config.NamespaceFilter
+	namespaceSelector  labels.Selector
+	policy             *config.Policy
+	filter             *config.FieldFilter
+	cache              *secretsCache
+	heartbeat          time.Duration
+	controllers        map[string]*SecretsHandler
+	// defaultController is used if no controller is selected, like the default sealer
+	defaultController string
 }
 
-// NewHandler creates a new secret handler.
+// NewHandler creates a new secret handler. The metadata client checks if secrets exist without loading their data.
 func NewHandler(
 	coreClient corev1.CoreV1Interface,
 	ssCl ssClient.BitnamiV1alpha1Interface,
+	metadataClient metadata.Interface,
 	cfg *config.Config,
 ) *SecretsHandler {
-	inMap := make(map[string]bool)
-	for _, n := range cfg.IncludeNamespaces {
-		inMap[n] = true
+	var selector labels.Selector
+	if cfg.NamespaceSelector != "" {
+		// the selector is validated when parsing the config
+		selector, _ = labels.Parse(cfg.NamespaceSelector)
 	}
+	defaultController, _ := cfg.Controller("")
 	return &SecretsHandler{
 		ssClient:           ssCl,
 		coreClient:         coreClient,
+		metadataClient:     metadataClient,
+		namespaceClient:    coreClient,
 		disableLoadSecrets: cfg.DisableLoadSecrets,
-		includeNamespaces:  inMap,
+		namespaceFilter:    cfg.NamespaceFilter(),
+		namespaceSelector:  selector,
+		policy:             cfg.Policy(),
+		filter:             cfg.FieldFilter,
+		heartbeat:          defaultHeartbeat,
+		controllers:        make(map[string]*SecretsHandler),
+		defaultController:  defaultController.Name,
 	}
 }
 
-// List returns a list of all secrets.
-func (h *SecretsHandler) list(ctx context.Context) ([]Secret, error) {
-	var secrets []Secret
-	fmt.Printf("secrets: %#v\n", secrets)
+// AddController registers the clients to be used for the given controller.
+func (h *SecretsHandler) AddController(
+	name string,
+	coreClient corev1.CoreV1Interface,
+	ssCl ssClient.BitnamiV1alpha1Interface,
+	metadataClient metadata.Interface,
+) {
+	ch := *h
+	ch.coreClient = coreClient
+	ch.ssClient = ssCl
+	ch.metadataClient = metadataClient
+	ch.namespaceClient = coreClient
+	ch.controllers = nil
+	h.controllers[name] = &ch
+}
+
+// Impersonate reads the secrets of the controller with the clients of the session user. An empty name selects
+// the default controller, controllers added afterwards use the same clients.
+// The namespaces are still resolved with the clients of the controller.
+func (h *SecretsHandler) Impersonate(name string, clientsFor ClientsFor) {
+	if name == "" {
+		h.clientsFor = clientsFor
+		return
+	}
+	if ch, ok := h.controllers[name]; ok {
+		ch.clientsFor = clientsFor
+	}
+}
+
+// StartCache starts informer caches for the default and all added controllers.
+// Until the caches are synced, the api server is called directly.
+func (h *SecretsHandler) StartCache(ctx context.Context) {
+	if h.disableLoadSecrets {
+		return
+	}
+	namespaces, _ := h.watchNamespaces()
+	caches := make(map[ssClient.BitnamiV1alpha1Interface]*secretsCache)
+	for _, sh := range h.handlers() {
+		if sh.ssClient == nil || sh.coreClient == nil {
+			continue
+		}
+		if c, ok := caches[sh.ssClient]; ok {
+			sh.cache = c
+			continue
+		}
+		sh.cache = newSecretsCache(sh.coreClient, sh.ssClient, namespaces)
+		sh.cache.start(ctx)
+		caches[sh.ssClient] = sh.cache
+	}
+}
+
+// Ready reports if all caches are synced, it is used as readiness probe.
+func (h *SecretsHandler) Ready(c *gin.Context) {
+	for _, sh := range h.handlers() {
+		if sh.cache != nil && !sh.cache.synced() {
+			c.String(http.StatusServiceUnavailable, "caches not synced")
+			return
+		}
+	}
+	c.String(http.StatusOK, "OK")
+}
+
+// handlers returns the handlers of all controllers, and the handler itself if the default controller was not added.
+func (h *SecretsHandler) handlers() []*SecretsHandler {
+	var handlers []*SecretsHandler
+	if _, ok := h.controllers[h.defaultController]; !ok {
+		handlers = append(handlers, h)
+	}
+	for _, name := range slices.Sorted(maps.Keys(h.controllers)) {
+		handlers = append(handlers, h.controllers[name])
+	}
+	return handlers
+}
+
+// cached returns true if the caches can be used.
+func (h *SecretsHandler) cached() bool {
+	return h.cache != nil && h.cache.synced()
+}
+
+// forController returns the handler of the controller selected with the 'controller' query parameter.
+// If no controller is selected, the handler of the default controller is returned, which is the controller
+// the default sealer seals for. The handler itself is only used if the default controller was not added.
+// With impersonation, the returned handler uses the clients of the session user.
+func (h *SecretsHandler) forController(c *gin.Context) (*SecretsHandler, error) {
+	name := c.Query("controller")
+	if name == "" {
+		name = h.defaultController
+	}
+	ch, ok := h.controllers[name]
+	switch {
+	case ok:
+	case name == h.defaultController:
+		ch = h
+	default:
+		return nil, fmt.Errorf("unknown controller '%s'", Sanitize(name))
+	}
+	return ch.forUser(c)
+}
+
+// forUser returns a copy of the handler with the clients of the session user if impersonation is enabled.
+// The copy does not use the cache, as it would bypass the permissions of the user.
+func (h *SecretsHandler) forUser(c *gin.Context) (*SecretsHandler, error) {
+	if h.clientsFor == nil {
+		return h, nil
+	}
+	session, ok := sessionData(c)
+	if !ok || session.UserInfo.Username == "" {
+		return nil, errNoSession
+	}
+	coreClient, ssCl, metadataClient, err := h.clientsFor(session.UserInfo)
+	if err != nil {
+		return nil, err
+	}
+	uh := *h
+	uh.coreClient = coreClient
+	uh.ssClient = ssCl
+	uh.metadataClient = metadataClient
+	uh.cache = nil
+	return &uh, nil
+}
+
+// list returns a page of the secrets matching the query.
+// Namespaces are listed one after the other, the cursor points to the namespace to continue with.
+// The name, status and secretExists filters can't be passed to the api server, so the sealed secrets are listed
+// in chunks of the page size and filtered until the page is full. Their cursor remembers the last listed secret,
+// as the page may end within a chunk.
+func (h *SecretsHandler) list(ctx context.Context, q secretQuery) (*SecretList, error) {
+	result := &SecretList{Secrets: []Secret{}}
 	if h.disableLoadSecrets {
-		return secrets, nil
+		return result, nil
+	}
+
+	namespaces, err := h.namespaces(ctx, q.namespace)
+	if err != nil {
+		return nil, err
+	}
+	if q.allowed != nil {
+		if namespaces, err = h.restrictNamespaces(ctx, namespaces, q.allowed); err != nil {
+			return nil, err
+		}
+	}
+	if q.cursor != nil && q.cursor.Cached {
+		if !h.cached() {
+			return nil, errInvalidContinue
+		}
+		return h.listCached(namespaces, q), nil
+	}
+	if q.cursor == nil && h.cached() {
+		return h.listCached(namespaces, q), nil
+	}
+	filtered := q.filtered()
+	if q.cursor != nil && q.cursor.Filtered != filtered {
+		return nil, errInvalidContinue
+	}
+
+	start, token, after, offset := 0, "", "", int64(0)
+	if q.cursor != nil {
+		start = slices.Index(namespaces, q.cursor.Namespace)
+		if start < 0 {
+			return nil, errInvalidContinue
+		}
+		token, after, offset = q.cursor.Continue, q.cursor.After, q.cursor.Offset
 	}
 
-	if len(h.includeNamespaces) > 0 {
-		for ns := range h.includeNamespaces {
-			list, err := h.listForNamespace(ctx, ns)
+	var remaining *int64
+	var next *secretCursor
+	lookup := h.secretLookup()
+	full := func() bool {
+		return q.limit > 0 && int64(len(result.Secrets)) >= q.limit
+	}
+	for i := start; i < len(namespaces) && next == nil; i++ {
+		for {
+			opts := metav1.ListOptions{LabelSelector: q.labelSelector, FieldSelector: q.fieldSelector}
+			if q.limit > 0 {
+				opts.Limit = q.limit - int64(len(result.Secrets))
+				if filtered {
+					opts.Limit = q.limit
+				}
+				opts.Continue = token
+			}
+			ssList, err := h.ssClient.SealedSecrets(namespaces[i]).List(ctx, opts)
 			if err != nil {
 				return nil, err
 			}
-			secrets = append(secrets, list...)
+			items := ssList.Items
+			if after != "" {
+				// skips the secrets of the chunk listed on the previous page
+				items = slices.DeleteFunc(items, func(item v1alpha1.SealedSecret) bool {
+					return secretKey(item.Namespace, item.Name) <= after
+				})
+				after = ""
+			}
+			secrets, err := h.toSecrets(ctx, lookup, items)
+			if err != nil {
+				return nil, err
+			}
+			for _, secret := range q.apply(secrets) {
+				if full() {
+					// the page ends within the chunk, the next page lists the chunk again
+					last := result.Secrets[len(result.Secrets)-1]
+					next = &secretCursor{Namespace: namespaces[i], Continue: token, After: secretKey(last.Namespace, last.Name)}
+					break
+				}
+				result.Secrets = append(result.Secrets, secret)
+			}
+			if next != nil {
+				break
+			}
+			token = ssList.Continue
+			if token == "" {
+				break
+			}
+			if full() {
+				next = &secretCursor{Namespace: namespaces[i], Continue: token}
+				if i == len(namespaces)-1 && !filtered {
+					remaining = ssList.RemainingItemCount
+				}
+				break
+			}
 		}
-	} else {
-		list, err := h.listForNamespace(ctx, "")
-		if err != nil {
-			return nil, err
+		if next == nil && full() && i < len(namespaces)-1 {
+			next = &secretCursor{Namespace: namespaces[i+1]}
+		}
+	}
+
+	sortSecrets(result.Secrets)
+
+	// the total is only known on the last page, or if the api server counted the remaining items
+	listed := offset + int64(len(result.Secrets))
+	switch {
+	case next == nil:
+		result.Total = &listed
+	case remaining != nil:
+		total := listed + *remaining
+		result.Total = &total
+	}
+	if next != nil {
+		next.Offset, next.Filtered = listed, filtered
+		result.Continue = next.encode()
+	}
+	return result, nil
+}
+
+// secretKey returns the key of a secret, which orders the secrets like the api server does.
+func secretKey(namespace, name string) string {
+	return namespace + "/" + name
+}
+
+// listCached returns a page of the cached secrets. All filters are applied before paging,
+// so the total is always known.
+func (h *SecretsHandler) listCached(namespaces []string, q secretQuery) *SecretList {
+	labelSelector, _ := labels.Parse(q.labelSelector)
+	fieldSelector, _ := fields.ParseSelector(q.fieldSelector)
+
+	var secrets []Secret
+	for _, ns := range namespaces {
+		for _, item := range h.cache.listSealedSecrets(ns, labelSelector, fieldSelector) {
+			status, message := sealedSecretStatus(&item)
+			_, err := h.cache.getSecret(item.Namespace, item.Name)
+			exists := err == nil
+			secrets = append(secrets, Secret{
+				Namespace:    item.Namespace,
+				Name:         item.Name,
+				Status:       status,
+				Message:      message,
+				SecretExists: &exists,
+			})
 		}
-		secrets = append(secrets, list...)
 	}
+	secrets = q.apply(secrets)
 
+	total := int64(len(secrets))
+	result := &SecretList{Secrets: []Secret{}, Total: &total}
+	start, end := int64(0), total
+	if q.cursor != nil {
+		start = min(q.cursor.Offset, total)
+	}
+	if q.limit > 0 && start+q.limit < total {
+		end = start + q.limit
+		result.Continue = (&secretCursor{Offset: end, Cached: true}).encode()
+	}
+	result.Secrets = append(result.Secrets, secrets[start:end]...)
+	return result
+}
+
+// sortSecrets sorts the secrets by namespace and name.
+func sortSecrets(secrets []Secret) {
 	sort.Slice(secrets, func(i, j int) bool {
 		if secrets[i].Namespace == secrets[j].Namespace {
 			return secrets[i].Name < secrets[j].Name
 		}
 		return secrets[i].Namespace < secrets[j].Namespace
 	})
+}
 
+// toSecrets evaluates the status of the sealed secrets and checks if their secrets exist.
+func (h *SecretsHandler) toSecrets(
+	ctx context.Context,
+	lookup *secretLookup,
+	items []v1alpha1.SealedSecret,
+) ([]Secret, error) {
+	secrets := make([]Secret, 0, len(items))
+	for _, item := range items {
+		status, message := sealedSecretStatus(&item)
+		secrets = append(secrets, Secret{
+			Namespace: item.Namespace,
+			Name:      item.Name,
+			Status:    status,
+			Message:   message,
+		})
+	}
+	if err := lookup.check(ctx, secrets); err != nil {
+		return nil, err
+	}
 	return secrets, nil
 }
 
-func (h *SecretsHandler) listForNamespace(ctx context.Context, ns string) ([]Secret, error) {
-	var secrets []Secret
-	ssList, err := h.ssClient.SealedSecrets(ns).List(ctx, metav1.ListOptions{})
+// secretsResource is the resource of the secrets for the metadata client.
+var secretsResource = v1.SchemeGroupVersion.WithResource("secrets")
+
+// secretLookup checks if the secrets of sealed secrets exist. The secrets of a namespace are listed once with the
+// metadata client, so their data is never loaded. If the user is not allowed to list the secrets of a namespace,
+// the existence of its secrets is unknown.
+type secretLookup struct {
+	client metadata.Interface
+	// names are the names of the secrets by namespace, nil if the secrets can't be listed
+	names map[string]map[string]bool
+}
+
+func (h *SecretsHandler) secretLookup() *secretLookup {
+	return &secretLookup{client: h.metadataClient, names: make(map[string]map[string]bool)}
+}
+
+// check sets if the secrets exist, it is left empty if that is unknown.
+func (l *secretLookup) check(ctx context.Context, secrets []Secret) error {
+	for i := range secrets {
+		names, err := l.namespace(ctx, secrets[i].Namespace)
+		if err != nil {
+			return err
+		}
+		if names != nil {
+			exists := names[secrets[i].Name]
+			secrets[i].SecretExists = &exists
+		}
+	}
+	return nil
+}
+
+// namespace returns the names of the secrets in the namespace, or nil if they can't be listed.
+func (l *secretLookup) namespace(ctx context.Context, namespace string) (map[string]bool, error) {
+	if names, ok := l.names[namespace]; ok || l.client == nil {
+		return names, nil
+	}
+	list, err := l.client.Resource(secretsResource).Namespace(namespace).List(ctx, metav1.ListOptions{})
+	if apierrors.IsForbidden(err) {
+		l.names[namespace] = nil
+		return nil, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	for _, item := range ssList.Items {
-		secrets = append(secrets, Secret{Namespace: item.Namespace, Name: item.Name})
+	names := make(map[string]bool, len(list.Items))
+	for _, item := range list.Items {
+		names[item.Name] = true
+	}
+	l.names[namespace] = names
+	return names, nil
+}
+
+// secretExists checks if the secret of a single sealed secret exists, with the cache or the metadata of the secret.
+// It returns nil if the user is not allowed to read the secret.
+func (h *SecretsHandler) secretExists(ctx context.Context, namespace, name string) (*bool, error) {
+	if h.cached() {
+		if _, err := h.cache.getSecret(namespace, name); !errors.Is(err, errNotCached) {
+			exists := err == nil
+			return &exists, nil
+		}
+	}
+	if h.metadataClient == nil {
+		return nil, nil
+	}
+	_, err := h.metadataClient.Resource(secretsResource).Namespace(namespace).Get(ctx, name, metav1.GetOptions{})
+	switch {
+	case apierrors.IsForbidden(err):
+		return nil, nil
+	case err != nil && !apierrors.IsNotFound(err):
+		return nil, err
+	}
+	exists := err == nil
+	return &exists, nil
+}
+
+// sealedSecretStatus evaluates the sync status and the message of the last condition of the sealed secret.
+// The secret is pending as long as the controller did not observe the current generation.
+func sealedSecretStatus(ss *v1alpha1.SealedSecret) (SecretStatus, string) {
+	if ss.Status == nil || ss.Status.ObservedGeneration < ss.Generation {
+		return SecretStatusPending, ""
+	}
+	var last *v1alpha1.SealedSecretCondition
+	for i := range ss.Status.Conditions {
+		cond := &ss.Status.Conditions[i]
+		if cond.Type != v1alpha1.SealedSecretSynced {
+			continue
+		}
+		if last == nil || last.LastUpdateTime.Before(&cond.LastUpdateTime) {
+			last = cond
+		}
+	}
+	if last == nil {
+		return SecretStatusPending, ""
+	}
+	switch last.Status {
+	case v1.ConditionTrue:
+		return SecretStatusSynced, last.Message
+	case v1.ConditionFalse:
+		return SecretStatusFailed, last.Message
+	default:
+		return SecretStatusPending, last.Message
 	}
-	return secrets, nil
 }
 
 // GetSecret returns a secret by name in the given namespace.
@@ -123,10 +575,10 @@ func (h *SecretsHandler) GetSecret(ctx context.Context, namespace, name string)
 	if h.disableLoadSecrets {
 		return nil, nil
 	}
-	if len(h.includeNamespaces) > 0 && !h.includeNamespaces[namespace] {
-		return nil, fmt.Errorf("namespace '%s' is not allowed", namespace)
+	if err := h.namespaceAllowed(ctx, namespace); err != nil {
+		return nil, err
 	}
-	secret, err := h.coreClient.Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+	secret, err := h.getSecret(ctx, namespace, name)
 	if err != nil {
 		return nil, err
 	}
@@ -143,20 +595,101 @@ func (h *SecretsHandler) GetSecret(ctx context.Context, namespace, name string)
 	return secret, nil
 }
 
+// getSecret returns the secret from the cache, or from the api server if it is not cached.
+func (h *SecretsHandler) getSecret(ctx context.Context, namespace, name string) (*v1.Secret, error) {
+	if h.cached() {
+		if secret, err := h.cache.getSecret(namespace, name); !errors.Is(err, errNotCached) {
+			return secret, err
+		}
+	}
+	return h.coreClient.Secrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// getSealedSecret returns the sealed secret from the cache, or from the api server if it is not cached.
+func (h *SecretsHandler) getSealedSecret(ctx context.Context, namespace, name string) (*v1alpha1.SealedSecret, error) {
+	if h.cached() {
+		if ss, err := h.cache.getSealedSecret(namespace, name); !errors.Is(err, errNotCached) {
+			return ss, err
+		}
+	}
+	return h.ssClient.SealedSecrets(namespace).Get(ctx, name, metav1.GetOptions{})
+}
+
+// GetSealedSecret returns the sealed secret by name in the given namespace, cleaned of all server side fields.
+func (h *SecretsHandler) GetSealedSecret(ctx context.Context, namespace, name string) (*unstructured.Unstructured, error) {
+	if err := h.namespaceAllowed(ctx, namespace); err != nil {
+		return nil, err
+	}
+	ss, err := h.getSealedSecret(ctx, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	ss.TypeMeta = metav1.TypeMeta{
+		APIVersion: v1alpha1.SchemeGroupVersion.String(),
+		Kind:       "SealedSecret",
+	}
+
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(ss)
+	if err != nil {
+		return nil, err
+	}
+	config.ServerSideFields.Apply(obj)
+	if h.filter != nil {
+		h.filter.Apply(obj)
+	}
+	if annotations, ok, _ := unstructured.NestedMap(obj, "metadata", "annotations"); ok && len(annotations) == 0 {
+		unstructured.RemoveNestedField(obj, "metadata", "annotations")
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
 func (h *SecretsHandler) AllSecrets(c *gin.Context) {
 	if h.disableLoadSecrets {
 		c.JSON(http.StatusForbidden, gin.H{"error": "Loading secrets is disabled"})
 		return
 	}
 
-	sec, err := h.list(c)
+	ch, err := h.forController(c)
+	if err != nil {
+		c.JSON(forbiddenStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	q, err := parseSecretQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if q.namespace != "" {
+		if err := authorize(c, ch.policy, config.OperationList, q.namespace); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ch.reviewAccess(c, "list", q.namespace); err != nil {
+			c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+	} else {
+		reviewed, err := ch.reviewedNamespaces(c, "list")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		q.allowed = allNamespaces(allowedNamespaces(c, ch.policy, config.OperationList), reviewed)
+	}
+
+	list, err := ch.list(c, q)
 	if err != nil {
 		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		if errors.Is(err, errInvalidContinue) || apierrors.IsResourceExpired(err) || apierrors.IsBadRequest(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"secrets": sec})
+	c.JSON(http.StatusOK, list)
 }
 
 func (h *SecretsHandler) Secret(c *gin.Context) {
@@ -170,13 +703,28 @@ func (h *SecretsHandler) Secret(c *gin.Context) {
 		return
 	}
 
+	ch, err := h.forController(c)
+	if err != nil {
+		c.JSON(forbiddenStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
 	// Load existing secret.
 	namespace := Sanitize(c.Param("namespace"))
 	name := Sanitize(c.Param("name"))
-	secret, err := h.GetSecret(c, namespace, name)
+	if err := authorize(c, ch.policy, config.OperationRead, namespace); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err := ch.reviewAccess(c, "get", namespace); err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	secret, err := ch.GetSecret(c, namespace, name)
 	if err != nil {
 		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
 		return
 	}
 
@@ -189,7 +737,55 @@ func (h *SecretsHandler) Secret(c *gin.Context) {
 	c.Data(http.StatusOK, contentType, encode)
 }
 
+// SealedSecret returns the sealed secret manifest as it can be stored in Git.
+func (h *SecretsHandler) SealedSecret(c *gin.Context) {
+	contentType, outputFormat, done := NegotiateFormat(c)
+	if done {
+		return
+	}
+
+	if h.disableLoadSecrets {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Loading secrets is disabled"})
+		return
+	}
+
+	ch, err := h.forController(c)
+	if err != nil {
+		c.JSON(forbiddenStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+
+	namespace := Sanitize(c.Param("namespace"))
+	name := Sanitize(c.Param("name"))
+	if err := authorize(c, ch.policy, config.OperationRead, namespace); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	ss, err := ch.GetSealedSecret(c, namespace, name)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		if apierrors.IsNotFound(err) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	encode, err := encodeUnstructured(ss, outputFormat)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, contentType, encode)
+}
+
 func encodeSecret(secret *v1.Secret, outputFormat string) ([]byte, error) {
+	return encodeObject(secret, outputFormat)
+}
+
+func encodeObject(obj runtime.Object, outputFormat string) ([]byte, error) {
 	var contentType string
 	switch strings.ToLower(outputFormat) {
 	case "json", "":
@@ -211,10 +807,41 @@ func encodeSecret(secret *v1.Secret, outputFormat string) ([]byte, error) {
 	}
 	encoder := scheme.Codecs.EncoderForVersion(prettyEncoder, schema.GroupVersion{Group: "", Version: "v1"})
 
-	return runtime.Encode(encoder, secret)
+	return runtime.Encode(encoder, obj)
+}
+
+func encodeUnstructured(obj *unstructured.Unstructured, outputFormat string) ([]byte, error) {
+	var opts jsonserializer.SerializerOptions
+	switch strings.ToLower(outputFormat) {
+	case "json", "":
+		opts.Pretty = true
+	case "yaml":
+		opts.Yaml = true
+	default:
+		return nil, fmt.Errorf("unsupported output format: %s", outputFormat)
+	}
+	return runtime.Encode(jsonserializer.NewSerializerWithOptions(jsonserializer.DefaultMetaFactory, nil, nil, opts), obj)
 }
 
+// Secret is a sealed secret with its sync status. Message is the message of the last sync condition,
+// SecretExists tells if the secret managed by the sealed secret exists, it is omitted if the user is not allowed
+// to list the secrets.
 type Secret struct {
-	Namespace string `json:"namespace" yaml:"namespace"`
-	Name      string `json:"name"      yaml:"name"`
+	Namespace    string       `json:"namespace"              yaml:"namespace"`
+	Name         string       `json:"name"                   yaml:"name"`
+	Status       SecretStatus `json:"status"                 yaml:"status"`
+	Message      string       `json:"message,omitempty"      yaml:"message,omitempty"`
+	SecretExists *bool        `json:"secretExists,omitempty" yaml:"secretExists,omitempty"`
 }
+
+// SecretStatus is the sync status of a sealed secret.
+type SecretStatus string
+
+const (
+	// SecretStatusSynced the controller unsealed the secret successfully.
+	SecretStatusSynced SecretStatus = "synced"
+	// SecretStatusFailed the controller failed to unseal the secret.
+	SecretStatusFailed SecretStatus = "failed"
+	// SecretStatusPending the controller did not (yet) process the current version of the sealed secret.
+	SecretStatusPending SecretStatus = "pending"
+)
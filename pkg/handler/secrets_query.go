@@ -0,0 +1,155 @@
+package handler
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+var errInvalidContinue = errors.New("invalid continue token")
+
+// SecretList is a page of secrets. Continue is the cursor of the next page and empty on the last page.
+// Total is the number of all matching secrets, it is omitted if it can't be determined without loading all pages.
+type SecretList struct {
+	Secrets  []Secret `json:"secrets"            yaml:"secrets"`
+	Continue string   `json:"continue,omitempty" yaml:"continue,omitempty"`
+	Total    *int64   `json:"total,omitempty"    yaml:"total,omitempty"`
+}
+
+// secretQuery selects the secrets to be listed. Label and field selectors as well as the page size are passed
+// to the api server. All other filters are applied to each listed chunk of secrets.
+type secretQuery struct {
+	secretFilter
+	namespace     string
+	labelSelector string
+	fieldSelector string
+	limit         int64
+	cursor        *secretCursor
+	// allowed restricts the listed namespaces to the ones the user is allowed to list, nil allows all.
+	allowed func(namespace string) bool
+}
+
+// secretFilter filters the listed secrets by name, status and if their secret exists. Empty values match all secrets.
+// Secrets whose existence is unknown don't match the secretExists filter.
+type secretFilter struct {
+	name         string
+	status       *SecretStatus
+	secretExists *bool
+}
+
+// secretCursor is the position to continue listing from.
+type secretCursor struct {
+	Namespace string `json:"ns"`
+	Continue  string `json:"c,omitempty"`
+	// After is the key of the last listed secret if the page ended within the chunk listed with Continue
+	After string `json:"a,omitempty"`
+	// Offset is the number of sealed secrets listed before
+	Offset int64 `json:"o"`
+	// Cached is true if the page was listed from the cache, Offset is then the index of the next secret
+	Cached bool `json:"cached,omitempty"`
+	// Filtered is true if the secrets were filtered after loading them, Offset only counts the matching secrets
+	Filtered bool `json:"filtered,omitempty"`
+}
+
+// parseSecretQuery parses the query parameters of the secrets list.
+func parseSecretQuery(c *gin.Context) (secretQuery, error) {
+	q := secretQuery{
+		namespace:     c.Query("namespace"),
+		labelSelector: c.Query("labelSelector"),
+		fieldSelector: c.Query("fieldSelector"),
+	}
+	q.name = strings.ToLower(c.Query("name"))
+
+	if value := c.Query("status"); value != "" {
+		status := SecretStatus(strings.ToLower(value))
+		switch status {
+		case SecretStatusSynced, SecretStatusFailed, SecretStatusPending:
+			q.status = &status
+		default:
+			return q, fmt.Errorf("invalid status '%s'", Sanitize(value))
+		}
+	}
+	if value := c.Query("secretExists"); value != "" {
+		exists, err := strconv.ParseBool(value)
+		if err != nil {
+			return q, fmt.Errorf("invalid secretExists '%s'", Sanitize(value))
+		}
+		q.secretExists = &exists
+	}
+	if _, err := labels.Parse(q.labelSelector); err != nil {
+		return q, fmt.Errorf("invalid labelSelector: %w", err)
+	}
+	if _, err := fields.ParseSelector(q.fieldSelector); err != nil {
+		return q, fmt.Errorf("invalid fieldSelector: %w", err)
+	}
+	if value := c.Query("limit"); value != "" {
+		limit, err := strconv.ParseInt(value, 10, 64)
+		if err != nil || limit < 0 {
+			return q, fmt.Errorf("invalid limit '%s'", Sanitize(value))
+		}
+		q.limit = limit
+	}
+	if value := c.Query("continue"); value != "" {
+		if q.limit == 0 {
+			return q, errors.New("continue requires a limit")
+		}
+		cursor, err := decodeSecretCursor(value)
+		if err != nil {
+			return q, err
+		}
+		if q.namespace != "" && !cursor.Cached && cursor.Namespace != q.namespace {
+			return q, errInvalidContinue
+		}
+		q.cursor = cursor
+	}
+	return q, nil
+}
+
+// filtered returns true if secrets are filtered after loading them, the total is then only known on the last page.
+func (f secretFilter) filtered() bool {
+	return f.name != "" || f.status != nil || f.secretExists != nil
+}
+
+func (f secretFilter) apply(secrets []Secret) []Secret {
+	if !f.filtered() {
+		return secrets
+	}
+	filtered := make([]Secret, 0, len(secrets))
+	for _, s := range secrets {
+		if f.name != "" && !strings.Contains(strings.ToLower(s.Name), f.name) {
+			continue
+		}
+		if f.status != nil && s.Status != *f.status {
+			continue
+		}
+		if f.secretExists != nil && (s.SecretExists == nil || *s.SecretExists != *f.secretExists) {
+			continue
+		}
+		filtered = append(filtered, s)
+	}
+	return filtered
+}
+
+func (c *secretCursor) encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSecretCursor(value string) (*secretCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, errInvalidContinue
+	}
+	var c secretCursor
+	if err := json.Unmarshal(b, &c); err != nil {
+		return nil, errInvalidContinue
+	}
+	return &c, nil
+}
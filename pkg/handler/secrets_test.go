@@ -0,0 +1,448 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gattma/sealed-secrets-web/pkg/mocks/core"
+	"github.com/gattma/sealed-secrets-web/pkg/mocks/ssclient"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	metadatafake "k8s.io/client-go/metadata/fake"
+	clienttesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("Secrets", func() {
+		var (
+			recorder     *httptest.ResponseRecorder
+			c            *gin.Context
+			mock         *gomock.Controller
+			alpha1Client *ssclient.MockBitnamiV1alpha1Interface
+			ssClient     *ssclient.MockSealedSecretInterface
+			coreClient   *core.MockCoreV1Interface
+			metaClient   *metadatafake.FakeMetadataClient
+			h            *SecretsHandler
+		)
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			mock = gomock.NewController(GinkgoT())
+			alpha1Client = ssclient.NewMockBitnamiV1alpha1Interface(mock)
+			ssClient = ssclient.NewMockSealedSecretInterface(mock)
+			coreClient = core.NewMockCoreV1Interface(mock)
+			metaClient = newMetadataClient()
+			h = NewHandler(coreClient, alpha1Client, metaClient, &config.Config{})
+		})
+
+		It("should list the secrets of the selected controller", func() {
+			otherAlpha1Client := ssclient.NewMockBitnamiV1alpha1Interface(mock)
+			otherCoreClient := core.NewMockCoreV1Interface(mock)
+			h.AddController("other", otherCoreClient, otherAlpha1Client, newMetadataClient())
+
+			otherAlpha1Client.EXPECT().SealedSecrets("").Return(ssClient)
+			ssClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(&v1alpha1.SealedSecretList{
+				Items: []v1alpha1.SealedSecret{
+					{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "b"}},
+				},
+			}, nil)
+
+			c.Request, _ = http.NewRequest("GET", "/api/secrets?controller=other", nil)
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(
+				`{"secrets":[{"namespace":"a","name":"b","status":"pending","secretExists":false}],"total":1}`,
+			))
+		})
+
+		It("should list the secrets of the default controller if none is selected", func() {
+			h = NewHandler(coreClient, alpha1Client, metaClient, &config.Config{
+				Controllers: []config.Controller{{Name: "first"}, {Name: "other"}},
+			})
+			firstAlpha1Client := ssclient.NewMockBitnamiV1alpha1Interface(mock)
+			firstCoreClient := core.NewMockCoreV1Interface(mock)
+			h.AddController("first", firstCoreClient, firstAlpha1Client, newMetadataClient())
+			h.AddController("other", core.NewMockCoreV1Interface(mock), ssclient.NewMockBitnamiV1alpha1Interface(mock), nil)
+
+			firstAlpha1Client.EXPECT().SealedSecrets("").Return(ssClient)
+			ssClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(&v1alpha1.SealedSecretList{
+				Items: []v1alpha1.SealedSecret{
+					{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "b"}},
+				},
+			}, nil)
+
+			c.Request, _ = http.NewRequest("GET", "/api/secrets", nil)
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(ContainSubstring(`"name":"b"`))
+		})
+
+		Context("status", func() {
+			BeforeEach(func() {
+				alpha1Client.EXPECT().SealedSecrets("").Return(ssClient)
+				ssClient.EXPECT().List(gomock.Any(), gomock.Any()).Return(&v1alpha1.SealedSecretList{
+					Items: []v1alpha1.SealedSecret{
+						sealedSecret("synced", 1, 1, v1.ConditionTrue, ""),
+						sealedSecret("failed", 2, 2, v1.ConditionFalse, "no key could decrypt secret"),
+						sealedSecret("outdated", 2, 1, v1.ConditionTrue, ""),
+						{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "new", Generation: 1}},
+					},
+				}, nil)
+				// only the metadata of the secrets is listed
+				Ω(metaClient.Tracker().Add(secretMetadata("ns", "synced"))).Should(Succeed())
+				Ω(metaClient.Tracker().Add(secretMetadata("ns", "outdated"))).Should(Succeed())
+			})
+
+			It("should report the sync status of the secrets", func() {
+				c.Request, _ = http.NewRequest("GET", "/api/secrets", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(recorder.Body.String()).Should(Equal(`{"secrets":[` +
+					`{"namespace":"ns","name":"failed","status":"failed","message":"no key could decrypt secret","secretExists":false},` +
+					`{"namespace":"ns","name":"new","status":"pending","secretExists":false},` +
+					`{"namespace":"ns","name":"outdated","status":"pending","secretExists":true},` +
+					`{"namespace":"ns","name":"synced","status":"synced","secretExists":true}],"total":4}`))
+				// the secrets of the namespace are listed once
+				Ω(metaClient.Actions()).Should(HaveLen(1))
+			})
+
+			It("should filter by status", func() {
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?status=Failed", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(recorder.Body.String()).Should(Equal(`{"secrets":[` +
+					`{"namespace":"ns","name":"failed","status":"failed","message":"no key could decrypt secret","secretExists":false}],"total":1}`))
+			})
+
+			It("should filter by name", func() {
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?name=SYNC", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(recorder.Body.String()).Should(Equal(`{"secrets":[` +
+					`{"namespace":"ns","name":"synced","status":"synced","secretExists":true}],"total":1}`))
+			})
+
+			It("should filter by existing secret", func() {
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?status=pending&secretExists=false", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(recorder.Body.String()).Should(Equal(`{"secrets":[` +
+					`{"namespace":"ns","name":"new","status":"pending","secretExists":false}],"total":1}`))
+			})
+
+			Context("without permission to list the secrets", func() {
+				BeforeEach(func() {
+					metaClient.PrependReactor("list", "secrets", func(clienttesting.Action) (bool, runtime.Object, error) {
+						return true, nil, apierrors.NewForbidden(v1.Resource("secrets"), "", errors.New("denied"))
+					})
+				})
+
+				It("should omit if the secrets exist", func() {
+					c.Request, _ = http.NewRequest("GET", "/api/secrets?name=sync", nil)
+					h.AllSecrets(c)
+
+					Ω(recorder.Code).Should(Equal(http.StatusOK))
+					Ω(recorder.Body.String()).Should(Equal(`{"secrets":[` +
+						`{"namespace":"ns","name":"synced","status":"synced"}],"total":1}`))
+				})
+
+				It("should not report unknown secrets as missing", func() {
+					c.Request, _ = http.NewRequest("GET", "/api/secrets?secretExists=false", nil)
+					h.AllSecrets(c)
+
+					Ω(recorder.Code).Should(Equal(http.StatusOK))
+					Ω(recorder.Body.String()).Should(Equal(`{"secrets":[],"total":0}`))
+				})
+			})
+		})
+
+		It("should return an error if the status filter is invalid", func() {
+			c.Request, _ = http.NewRequest("GET", "/api/secrets?status=foo", nil)
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"invalid status 'foo'"}`))
+		})
+
+		It("should return an error if the secretExists filter is invalid", func() {
+			c.Request, _ = http.NewRequest("GET", "/api/secrets?secretExists=maybe", nil)
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"invalid secretExists 'maybe'"}`))
+		})
+
+		It("should return an error if the controller is unknown", func() {
+			c.Request, _ = http.NewRequest("GET", "/api/secrets?controller=foo", nil)
+			h.AllSecrets(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"unknown controller 'foo'"}`))
+		})
+
+		Context("pagination", func() {
+			page := func(names ...string) *v1alpha1.SealedSecretList {
+				list := &v1alpha1.SealedSecretList{}
+				for _, n := range names {
+					list.Items = append(list.Items, v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: n}})
+				}
+				return list
+			}
+
+			It("should pass the selectors and the page size to the api server", func() {
+				remaining := int64(3)
+				list := page("a", "b")
+				list.Continue = "token"
+				list.RemainingItemCount = &remaining
+
+				alpha1Client.EXPECT().SealedSecrets("ns").Return(ssClient)
+				ssClient.EXPECT().List(gomock.Any(), metav1.ListOptions{
+					LabelSelector: "app=web",
+					FieldSelector: "metadata.name!=c",
+					Limit:         2,
+				}).Return(list, nil)
+				Ω(metaClient.Tracker().Add(secretMetadata("ns", "a"))).Should(Succeed())
+
+				c.Request, _ = http.NewRequest(
+					"GET",
+					"/api/secrets?namespace=ns&labelSelector=app%3Dweb&fieldSelector=metadata.name!%3Dc&limit=2",
+					nil,
+				)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				var result SecretList
+				Ω(json.Unmarshal(recorder.Body.Bytes(), &result)).Should(Succeed())
+				Ω(result.Secrets).Should(HaveLen(2))
+				Ω(result.Secrets[0].SecretExists).Should(HaveValue(BeTrue()))
+				Ω(result.Secrets[1].SecretExists).Should(HaveValue(BeFalse()))
+				Ω(result.Total).Should(HaveValue(Equal(int64(5))))
+
+				cursor, err := decodeSecretCursor(result.Continue)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(cursor).Should(Equal(&secretCursor{Namespace: "ns", Continue: "token", Offset: 2}))
+			})
+
+			It("should continue with the next page", func() {
+				alpha1Client.EXPECT().SealedSecrets("ns").Return(ssClient)
+				ssClient.EXPECT().List(gomock.Any(), metav1.ListOptions{Limit: 2, Continue: "token"}).Return(page("c"), nil)
+
+				cursor := (&secretCursor{Namespace: "ns", Continue: "token", Offset: 2}).encode()
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?namespace=ns&limit=2&continue="+cursor, nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(recorder.Body.String()).Should(Equal(
+					`{"secrets":[{"namespace":"ns","name":"c","status":"pending","secretExists":false}],"total":3}`,
+				))
+			})
+
+			It("should continue with the next included namespace", func() {
+				h = NewHandler(coreClient, alpha1Client, metaClient, &config.Config{IncludeNamespaces: []string{"ns", "other"}})
+
+				alpha1Client.EXPECT().SealedSecrets("ns").Return(ssClient)
+				ssClient.EXPECT().List(gomock.Any(), metav1.ListOptions{Limit: 1}).Return(page("a"), nil)
+
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?limit=1", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				var result SecretList
+				Ω(json.Unmarshal(recorder.Body.Bytes(), &result)).Should(Succeed())
+				Ω(result.Total).Should(BeNil())
+				cursor, err := decodeSecretCursor(result.Continue)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(cursor).Should(Equal(&secretCursor{Namespace: "other", Offset: 1}))
+			})
+
+			It("should fill the filtered page with chunks of the page size", func() {
+				first, second := page("a", "b1"), page("b2", "b3")
+				first.Continue, second.Continue = "token", "next"
+				alpha1Client.EXPECT().SealedSecrets("ns").Return(ssClient).Times(2)
+				ssClient.EXPECT().List(gomock.Any(), metav1.ListOptions{Limit: 2}).Return(first, nil)
+				ssClient.EXPECT().List(gomock.Any(), metav1.ListOptions{Limit: 2, Continue: "token"}).Return(second, nil)
+
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?namespace=ns&name=b&limit=2", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				var result SecretList
+				Ω(json.Unmarshal(recorder.Body.Bytes(), &result)).Should(Succeed())
+				Ω(result.Secrets).Should(HaveLen(2))
+				Ω(result.Secrets[0].Name).Should(Equal("b1"))
+				Ω(result.Secrets[1].Name).Should(Equal("b2"))
+				// counting the matching secrets would require listing all pages
+				Ω(result.Total).Should(BeNil())
+				cursor, err := decodeSecretCursor(result.Continue)
+				Ω(err).ShouldNot(HaveOccurred())
+				Ω(cursor).Should(Equal(&secretCursor{Namespace: "ns", Continue: "token", After: "ns/b2", Offset: 2, Filtered: true}))
+			})
+
+			It("should continue the filtered list after the last listed secret", func() {
+				Ω(metaClient.Tracker().Add(secretMetadata("ns", "b3"))).Should(Succeed())
+				alpha1Client.EXPECT().SealedSecrets("ns").Return(ssClient)
+				ssClient.EXPECT().List(gomock.Any(), metav1.ListOptions{Limit: 2, Continue: "token"}).Return(page("b2", "b3"), nil)
+
+				cursor := (&secretCursor{Namespace: "ns", Continue: "token", After: "ns/b2", Offset: 2, Filtered: true}).encode()
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?namespace=ns&name=b&limit=2&continue="+cursor, nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(recorder.Body.String()).Should(Equal(
+					`{"secrets":[{"namespace":"ns","name":"b3","status":"pending","secretExists":true}],"total":3}`,
+				))
+			})
+
+			It("should reject the cursor of an unfiltered list for a filtered list", func() {
+				cursor := (&secretCursor{Namespace: "ns", Continue: "token", Offset: 2}).encode()
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?namespace=ns&name=b&limit=2&continue="+cursor, nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"invalid continue token"}`))
+			})
+
+			It("should return an error if the continue token is invalid", func() {
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?limit=1&continue=foo", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"invalid continue token"}`))
+			})
+
+			It("should return an error if the label selector is invalid", func() {
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?labelSelector=a%3D%3D%3Db", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+				Ω(recorder.Body.String()).Should(ContainSubstring("invalid labelSelector"))
+			})
+
+			It("should not list namespaces that are not included", func() {
+				h = NewHandler(coreClient, alpha1Client, metaClient, &config.Config{IncludeNamespaces: []string{"ns"}})
+
+				c.Request, _ = http.NewRequest("GET", "/api/secrets?namespace=other", nil)
+				h.AllSecrets(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"namespace 'other' is not allowed"}`))
+			})
+		})
+
+		Context("SealedSecret", func() {
+			BeforeEach(func() {
+				cfg := &config.Config{FieldFilter: &config.FieldFilter{
+					SkipIfNil: [][]string{{"spec", "template", "metadata", "creationTimestamp"}},
+				}}
+				h = NewHandler(coreClient, alpha1Client, metaClient, cfg)
+				c.Params = gin.Params{{Key: "namespace", Value: "ns"}, {Key: "name", Value: "mysecret"}}
+			})
+
+			It("should return the cleaned sealed secret", func() {
+				alpha1Client.EXPECT().SealedSecrets("ns").Return(ssClient)
+				ssClient.EXPECT().Get(gomock.Any(), "mysecret", gomock.Any()).Return(&v1alpha1.SealedSecret{
+					ObjectMeta: metav1.ObjectMeta{
+						Namespace:       "ns",
+						Name:            "mysecret",
+						UID:             "1234",
+						ResourceVersion: "42",
+						Generation:      2,
+						Annotations: map[string]string{
+							"kubectl.kubernetes.io/last-applied-configuration": "{}",
+						},
+						ManagedFields: []metav1.ManagedFieldsEntry{{Manager: "kubectl"}},
+					},
+					Spec: v1alpha1.SealedSecretSpec{
+						EncryptedData: map[string]string{"username": "AgBy3i4OJSWK+PiTySYZZA=="},
+					},
+					Status: &v1alpha1.SealedSecretStatus{ObservedGeneration: 2},
+				}, nil)
+
+				c.Request, _ = http.NewRequest("GET", "/api/sealedsecret/ns/mysecret", nil)
+				c.Request.Header.Set("Accept", "application/yaml")
+				h.SealedSecret(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusOK))
+				Ω(recorder.Body.String()).Should(Equal(`apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: mysecret
+  namespace: ns
+spec:
+  encryptedData:
+    username: AgBy3i4OJSWK+PiTySYZZA==
+  template:
+    metadata: {}
+`))
+				Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/yaml"))
+			})
+
+			It("should return not found if the sealed secret does not exist", func() {
+				alpha1Client.EXPECT().SealedSecrets("ns").Return(ssClient)
+				ssClient.EXPECT().Get(gomock.Any(), "mysecret", gomock.Any()).Return(
+					nil, apierrors.NewNotFound(v1alpha1.Resource("sealedsecrets"), "mysecret"))
+
+				c.Request, _ = http.NewRequest("GET", "/api/sealedsecret/ns/mysecret", nil)
+				c.Request.Header.Set("Accept", "application/json")
+				h.SealedSecret(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusNotFound))
+			})
+
+			It("should not return sealed secrets of other namespaces", func() {
+				h = NewHandler(coreClient, alpha1Client, metaClient, &config.Config{IncludeNamespaces: []string{"other"}})
+
+				c.Request, _ = http.NewRequest("GET", "/api/sealedsecret/ns/mysecret", nil)
+				c.Request.Header.Set("Accept", "application/json")
+				h.SealedSecret(c)
+
+				Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+				Ω(recorder.Body.String()).Should(Equal(`{"error":"namespace 'ns' is not allowed"}`))
+			})
+		})
+	})
+})
+
+func sealedSecret(name string, generation, observed int64, synced v1.ConditionStatus, message string) v1alpha1.SealedSecret {
+	return v1alpha1.SealedSecret{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: name, Generation: generation},
+		Status: &v1alpha1.SealedSecretStatus{
+			ObservedGeneration: observed,
+			Conditions: []v1alpha1.SealedSecretCondition{
+				{Type: v1alpha1.SealedSecretSynced, Status: synced, Message: message},
+			},
+		},
+	}
+}
+
+// newMetadataClient returns a fake metadata client for the metadata of the given objects.
+func newMetadataClient(objects ...runtime.Object) *metadatafake.FakeMetadataClient {
+	scheme := metadatafake.NewTestScheme()
+	_ = metav1.AddMetaToScheme(scheme)
+	return metadatafake.NewSimpleMetadataClient(scheme, objects...)
+}
+
+// secretMetadata returns the metadata of a secret, as returned by the metadata client.
+func secretMetadata(namespace, name string) *metav1.PartialObjectMetadata {
+	return &metav1.PartialObjectMetadata{
+		TypeMeta:   metav1.TypeMeta{APIVersion: "v1", Kind: "Secret"},
+		ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name},
+	}
+}
@@ -0,0 +1,97 @@
+package handler
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"net/http"
+	"slices"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+)
+
+func (h *Handler) Unseal(c *gin.Context) {
+	contentType, outputFormat, done := NegotiateFormat(c)
+	if done {
+		return
+	}
+
+	if h.unsealer == nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unseal is disabled"})
+		return
+	}
+	if !h.isUnsealAdmin(c) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unseal is only allowed for admins"})
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	// the namespace is checked before decrypting, so the response does not tell if the keys can decrypt
+	// secrets of namespaces the user is not allowed to unseal in
+	meta, err := objectMetadata(string(body))
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "invalid sealed secret: " + err.Error()})
+		return
+	}
+	// cluster-wide sealed secrets can be used in all namespaces, so they are checked for all of them
+	namespace := meta.Namespace
+	if v1alpha1.SecretScope(meta) == v1alpha1.ClusterWideScope {
+		namespace = ""
+	}
+	if err := h.checkNamespace(c, config.OperationUnseal, namespace); err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+	secret, err := h.unsealer.Unseal(bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("Sealed secret %s/%s unsealed by %s\n", secret.Namespace, secret.Name, sessionUser(c))
+
+	encode, err := encodeSecret(secret, outputFormat)
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	c.Data(http.StatusOK, contentType, encode)
+}
+
+// isUnsealAdmin checks if the user of the current session is in one of the unseal admin groups.
+func (h *Handler) isUnsealAdmin(c *gin.Context) bool {
+	session, ok := sessionData(c)
+	if !ok {
+		return false
+	}
+	return slices.ContainsFunc(session.UserInfo.Groups, func(g string) bool {
+		return slices.Contains(h.cfg.Unseal.AdminGroups, g)
+	})
+}
+
+func sessionData(c *gin.Context) (*store.SessionData, bool) {
+	v, ok := c.Get("user_session")
+	if !ok {
+		return nil, false
+	}
+	session, ok := v.(*store.SessionData)
+	return session, ok && session != nil
+}
+
+func sessionUser(c *gin.Context) string {
+	if session, ok := sessionData(c); ok {
+		return session.UserInfo.Username
+	}
+	return "unknown"
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+
+	"github.com/gattma/sealed-secrets-web/pkg/auth/store"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gattma/sealed-secrets-web/pkg/mocks/seal"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("Unseal", func() {
+		var (
+			recorder *httptest.ResponseRecorder
+			c        *gin.Context
+			mock     *gomock.Controller
+			unsealer *seal.MockUnsealer
+			h        *Handler
+		)
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			recorder = httptest.NewRecorder()
+			c, _ = gin.CreateTestContext(recorder)
+			mock = gomock.NewController(GinkgoT())
+			unsealer = seal.NewMockUnsealer(mock)
+			h = &Handler{
+				unsealer: unsealer,
+				cfg: &config.Config{Unseal: config.Unseal{
+					Enabled:     true,
+					AdminGroups: []string{"admins"},
+				}},
+			}
+			c.Request, _ = http.NewRequest("POST", "/v1/unseal", bytes.NewReader([]byte(sealAsJSON)))
+			c.Request.Header.Set("Accept", "application/yaml")
+			c.Set("user_session", &store.SessionData{UserInfo: store.UserInfo{
+				Username: "admin",
+				Groups:   []string{"users", "admins"},
+			}})
+		})
+
+		It("should unseal the sealed secret for admins", func() {
+			unsealer.EXPECT().Unseal(gomock.Any()).Return(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "mysecret", Namespace: "myns"},
+				Data:       map[string][]byte{"username": []byte("admin")},
+			}, nil)
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+			Ω(recorder.Body.String()).Should(Equal(`apiVersion: v1
+data:
+  username: YWRtaW4=
+kind: Secret
+metadata:
+  creationTimestamp: null
+  name: mysecret
+  namespace: myns
+`))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("application/yaml"))
+		})
+
+		It("should return an error if unseal fails", func() {
+			unsealer.EXPECT().Unseal(gomock.Any()).Return(nil, errors.New("no key could decrypt secret"))
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusUnprocessableEntity))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"no key could decrypt secret"}`))
+		})
+
+		It("should be forbidden for non admins", func() {
+			c.Set("user_session", &store.SessionData{UserInfo: store.UserInfo{Groups: []string{"users"}}})
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"Unseal is only allowed for admins"}`))
+		})
+
+		It("should be forbidden if the policy does not allow to unseal in the namespace", func() {
+			policy, err := config.NewPolicy(config.Authorization{Rules: []config.AuthorizationRule{
+				{Groups: []string{"admins"}, Namespaces: []string{"other"}},
+			}})
+			Ω(err).ShouldNot(HaveOccurred())
+			h.policy = policy
+			c.Request, _ = http.NewRequest("POST", "/v1/unseal", bytes.NewReader([]byte(strictSealedSecret)))
+			c.Request.Header.Set("Accept", "application/yaml")
+			// the secret is not decrypted, so the response does not tell if the keys could decrypt it
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"user 'admin' is not allowed to unseal secrets in namespace 'myns'"}`))
+		})
+
+		It("should return an error if the sealed secret can't be read", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/unseal", bytes.NewReader([]byte("foo: [")))
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+			Ω(recorder.Body.String()).Should(ContainSubstring(`"error":"invalid sealed secret: `))
+		})
+
+		It("should be forbidden if the policy does not allow to unseal in all namespaces for cluster-wide secrets", func() {
+			policy, err := config.NewPolicy(config.Authorization{Rules: []config.AuthorizationRule{
+				{Groups: []string{"admins"}, Namespaces: []string{"myns"}},
+			}})
+			Ω(err).ShouldNot(HaveOccurred())
+			h.policy = policy
+			c.Request, _ = http.NewRequest("POST", "/v1/unseal", bytes.NewReader([]byte(clusterWideSealedSecret)))
+			c.Request.Header.Set("Accept", "application/yaml")
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"user 'admin' is not allowed to unseal cluster-wide secrets"}`))
+		})
+
+		It("should unseal cluster-wide secrets if the policy allows to unseal in all namespaces", func() {
+			policy, err := config.NewPolicy(config.Authorization{Rules: []config.AuthorizationRule{
+				{Groups: []string{"admins"}, Operations: []config.Operation{config.OperationUnseal}},
+			}})
+			Ω(err).ShouldNot(HaveOccurred())
+			h.policy = policy
+			c.Request, _ = http.NewRequest("POST", "/v1/unseal", bytes.NewReader([]byte(clusterWideSealedSecret)))
+			c.Request.Header.Set("Accept", "application/yaml")
+			unsealer.EXPECT().Unseal(gomock.Any()).Return(&v1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "mysecret", Namespace: "myns"},
+			}, nil)
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusOK))
+		})
+
+		It("should be forbidden without session", func() {
+			c.Keys = nil
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+		})
+
+		It("should be forbidden if disabled", func() {
+			h.unsealer = nil
+
+			h.Unseal(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusForbidden))
+			Ω(recorder.Body.String()).Should(Equal(`{"error":"Unseal is disabled"}`))
+		})
+	})
+})
+
+const strictSealedSecret = `apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: mysecret
+  namespace: myns
+spec:
+  encryptedData:
+    username: AgBy3i4OJSWK+PiTySYZZA==
+`
+
+const clusterWideSealedSecret = `apiVersion: bitnami.com/v1alpha1
+kind: SealedSecret
+metadata:
+  name: mysecret
+  namespace: myns
+  annotations:
+    sealedsecrets.bitnami.com/cluster-wide: "true"
+spec:
+  encryptedData:
+    username: AgBy3i4OJSWK+PiTySYZZA==
+`
@@ -1,20 +1,47 @@
 package handler
 
 import (
+	"bytes"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gattma/sealed-secrets-web/pkg/seal"
 	"github.com/gin-gonic/gin"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
 )
 
 func (h *Handler) Validate(c *gin.Context) {
-	if h.cfg.SealedSecrets.CertURL != "" {
-		configError := fmt.Errorf("validate can't be used with CertURL (%s)", h.cfg.SealedSecrets.CertURL)
+	sealer, err := h.sealerFor(c)
+	if err != nil {
+		c.Data(http.StatusBadRequest, "text/plain", []byte(err.Error()))
+		return
+	}
+	if configError := h.controllerServiceRequired(c, "validate"); configError != nil {
 		c.Data(http.StatusConflict, "text/plain", []byte(configError.Error()))
 		return
 	}
-	err := h.sealer.Validate(c, c.Request.Body)
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.Data(http.StatusBadRequest, "text/plain", []byte(err.Error()))
+		return
+	}
+	objects, err := readObjectsMetadata(scheme.Codecs.UniversalDecoder(), bytes.NewReader(body))
+	if err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.Data(http.StatusBadRequest, "text/plain", []byte(err.Error()))
+		return
+	}
+	if err := h.checkSealedNamespaces(c, sealer, config.OperationValidate, objects); err != nil {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		c.Data(namespaceStatus(err, http.StatusInternalServerError), "text/plain", []byte(err.Error()))
+		return
+	}
+	err = sealer.Validate(c, bytes.NewReader(body))
 
 	if err != nil {
 		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
@@ -23,3 +50,38 @@ func (h *Handler) Validate(c *gin.Context) {
 		c.Data(http.StatusOK, "text/plain", []byte("OK"))
 	}
 }
+
+// controllerServiceRequired returns an error if the selected controller can't be reached through its service,
+// which is required for the given action.
+func (h *Handler) controllerServiceRequired(c *gin.Context, action string) error {
+	ctrl, _ := h.cfg.Controller(c.Query("controller"))
+	if ctrl.Offline() {
+		return fmt.Errorf("%s can't be used in offline mode (%s)", action, ctrl.String())
+	}
+	if ctrl.CertURL != "" {
+		return fmt.Errorf("%s can't be used with CertURL (%s)", action, ctrl.CertURL)
+	}
+	return nil
+}
+
+// checkSealedNamespaces checks the namespaces the sealed secrets are sealed for.
+func (h *Handler) checkSealedNamespaces(
+	c *gin.Context,
+	sealer seal.Sealer,
+	op config.Operation,
+	objects []*metav1.PartialObjectMetadata,
+) error {
+	for i, obj := range objects {
+		namespace, err := targetNamespace(sealer, v1alpha1.DefaultScope, obj)
+		if err == nil {
+			err = h.checkNamespace(c, op, namespace)
+		}
+		if err != nil {
+			if len(objects) > 1 {
+				err = fmt.Errorf("document %d (%s): %w", i, obj.Name, err)
+			}
+			return err
+		}
+	}
+	return nil
+}
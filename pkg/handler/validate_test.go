@@ -8,6 +8,7 @@ import (
 
 	"github.com/gattma/sealed-secrets-web/pkg/config"
 	"github.com/gattma/sealed-secrets-web/pkg/mocks/seal"
+	ssw "github.com/gattma/sealed-secrets-web/pkg/seal"
 	"github.com/gin-gonic/gin"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -30,6 +31,7 @@ var _ = Describe("Handler ", func() {
 			c, _ = gin.CreateTestContext(recorder)
 			mock = gomock.NewController(GinkgoT())
 			sealer = seal.NewMockSealer(mock)
+			sealer.EXPECT().DefaultNamespace().Return("default", nil).AnyTimes()
 			cfg = &config.Config{}
 			h = &Handler{
 				sealer: sealer,
@@ -63,6 +65,46 @@ var _ = Describe("Handler ", func() {
 			Ω(recorder.Header().Get("Content-Type")).Should(Equal("text/plain"))
 		})
 
+		It("should return an error if certURL is used by the selected controller", func() {
+			cfg.Controllers = []config.Controller{
+				{Name: "cluster"},
+				{Name: "offline", SealedSecrets: config.SealedSecrets{CertURL: "http://sealed-secrets/v1/cert.pem"}},
+			}
+			h.sealers = map[string]ssw.Sealer{"cluster": sealer, "offline": sealer}
+			c.Request, _ = http.NewRequest(
+				"POST",
+				"/v1/validate?controller=offline",
+				bytes.NewReader([]byte(stringDataAsYAML)),
+			)
+			c.Request.Header.Set("Content-Type", "application/yaml")
+
+			h.Validate(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusConflict))
+		})
+
+		It("should return an error in offline mode", func() {
+			cfg.SealedSecrets.CertFile = "cert.pem"
+			c.Request, _ = http.NewRequest("POST", "/v1/validate", bytes.NewReader([]byte(stringDataAsYAML)))
+			c.Request.Header.Set("Content-Type", "application/yaml")
+
+			h.Validate(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusConflict))
+			Ω(recorder.Body.String()).Should(Equal("validate can't be used in offline mode (Cert file: cert.pem)"))
+			Ω(recorder.Header().Get("Content-Type")).Should(Equal("text/plain"))
+		})
+
+		It("should return an error if the controller is unknown", func() {
+			c.Request, _ = http.NewRequest("POST", "/v1/validate?controller=foo", bytes.NewReader([]byte(stringDataAsYAML)))
+			c.Request.Header.Set("Content-Type", "application/yaml")
+
+			h.Validate(c)
+
+			Ω(recorder.Code).Should(Equal(http.StatusBadRequest))
+			Ω(recorder.Body.String()).Should(Equal("unknown controller 'foo'"))
+		})
+
 		It("should return an error if certURL is used", func() {
 			cfg.SealedSecrets.CertURL = "http://sealed-secrets/v1/cert.pem"
 			c.Request, _ = http.NewRequest("POST", "/v1/validate", bytes.NewReader([]byte(stringDataAsYAML)))
@@ -0,0 +1,269 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+// defaultHeartbeat is the interval of the heartbeats keeping idle event streams open.
+const defaultHeartbeat = 30 * time.Second
+
+// Watch streams the added, modified and deleted sealed secrets as server-sent events.
+// The id of each event is the resource version, which is used to resume the stream with the Last-Event-ID header
+// or the 'resourceVersion' query parameter. If the resource version is expired or the stream can't be resumed,
+// an 'expired' event is sent and the client has to reload the secrets list before watching again.
+func (h *SecretsHandler) Watch(c *gin.Context) {
+	if h.disableLoadSecrets {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Loading secrets is disabled"})
+		return
+	}
+
+	ch, err := h.forController(c)
+	if err != nil {
+		c.JSON(forbiddenStatus(err, http.StatusBadRequest), gin.H{"error": err.Error()})
+		return
+	}
+	namespaces, filtered := ch.watchNamespaces()
+	var userAllowed func(namespace string) bool
+	if ns := c.Query("namespace"); ns != "" {
+		if err := ch.namespaceAllowed(c, ns); err != nil {
+			c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+		if err := authorize(c, ch.policy, config.OperationList, ns); err != nil {
+			c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+			return
+		}
+		if err := ch.reviewAccess(c, "list", ns); err != nil {
+			c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+			return
+		}
+		namespaces, filtered = []string{ns}, false
+	} else {
+		reviewed, err := ch.reviewedNamespaces(c, "list")
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			return
+		}
+		userAllowed = allNamespaces(allowedNamespaces(c, ch.policy, config.OperationList), reviewed)
+	}
+	resourceVersion := c.Query("resourceVersion")
+	if resourceVersion == "" {
+		resourceVersion = c.GetHeader("Last-Event-ID")
+	}
+
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+	events, done, filtered, err := ch.openWatch(ctx, namespaces, filtered, resourceVersion)
+	notResumable := errors.Is(err, errNotResumable)
+	if err != nil && !notResumable {
+		log.Printf("Error in %s: %v\n", Sanitize(c.Request.URL.Path), err)
+		if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+			c.JSON(http.StatusGone, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(forbiddenStatus(err, http.StatusInternalServerError), gin.H{"error": err.Error()})
+		return
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+	if notResumable {
+		// the client reloads the secrets and watches again without resource version
+		data, _ := json.Marshal(gin.H{"error": err.Error()})
+		writeSSE(c.Writer, "", "expired", string(data))
+		return
+	}
+	c.Writer.Flush()
+
+	heartbeat := time.NewTicker(ch.heartbeat)
+	defer heartbeat.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			_, _ = io.WriteString(c.Writer, ": heartbeat\n\n")
+		case <-done:
+			// the watch timed out, the client reconnects with the last event id
+			return
+		case ev := <-events:
+			if !ch.eventAllowed(ctx, ev, filtered, userAllowed) {
+				continue
+			}
+			if !ch.writeEvent(ctx, c.Writer, ev) {
+				return
+			}
+		}
+		c.Writer.Flush()
+	}
+}
+
+// errNotResumable is returned if the watches of several namespaces should be resumed from a resource version.
+var errNotResumable = errors.New("the namespaces are watched separately and can't be resumed, reload the secrets")
+
+// openWatch watches the sealed secrets of the namespaces. Several namespaces are watched with a single watch of all
+// namespaces if it is permitted, its events are filtered then. Only the events of a single watch are ordered by their
+// resource version, so separate watches of the namespaces can't be resumed from the last event id and
+// errNotResumable is returned for a resource version. It returns if the events have to be filtered.
+func (h *SecretsHandler) openWatch(
+	ctx context.Context,
+	namespaces []string,
+	filtered bool,
+	resourceVersion string,
+) (<-chan watch.Event, <-chan struct{}, bool, error) {
+	if len(namespaces) > 1 {
+		events, done, err := h.watch(ctx, []string{""}, resourceVersion)
+		if !apierrors.IsForbidden(err) {
+			return events, done, true, err
+		}
+		if resourceVersion != "" {
+			return nil, nil, filtered, errNotResumable
+		}
+	}
+	events, done, err := h.watch(ctx, namespaces, resourceVersion)
+	return events, done, filtered, err
+}
+
+// watch watches the sealed secrets of all namespaces. Without resource version, only changes from now on are watched.
+// The done channel is closed as soon as one watch ends, all watches are stopped when the context is done.
+func (h *SecretsHandler) watch(
+	ctx context.Context,
+	namespaces []string,
+	resourceVersion string,
+) (<-chan watch.Event, <-chan struct{}, error) {
+	var watchers []watch.Interface
+	stop := func() {
+		for _, w := range watchers {
+			w.Stop()
+		}
+	}
+	for _, ns := range namespaces {
+		opts := metav1.ListOptions{ResourceVersion: resourceVersion, AllowWatchBookmarks: true}
+		if opts.ResourceVersion == "" {
+			// an empty resource version would start with an added event for each existing sealed secret
+			list, err := h.ssClient.SealedSecrets(ns).List(ctx, metav1.ListOptions{Limit: 1})
+			if err != nil {
+				stop()
+				return nil, nil, err
+			}
+			opts.ResourceVersion = list.ResourceVersion
+		}
+		w, err := h.ssClient.SealedSecrets(ns).Watch(ctx, opts)
+		if err != nil {
+			stop()
+			return nil, nil, err
+		}
+		watchers = append(watchers, w)
+	}
+
+	events := make(chan watch.Event)
+	done := make(chan struct{})
+	var once sync.Once
+	for _, w := range watchers {
+		go func() {
+			defer once.Do(func() { close(done) })
+			for ev := range w.ResultChan() {
+				select {
+				case events <- ev:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	go func() {
+		<-ctx.Done()
+		stop()
+	}()
+	return events, done, nil
+}
+
+// eventAllowed returns false for events of sealed secrets in namespaces that are not allowed,
+// or that the user is not allowed to list. The namespace filter is only checked if the events are filtered.
+func (h *SecretsHandler) eventAllowed(
+	ctx context.Context,
+	ev watch.Event,
+	filtered bool,
+	userAllowed func(namespace string) bool,
+) bool {
+	ss, ok := ev.Object.(*v1alpha1.SealedSecret)
+	if !ok || ev.Type == watch.Bookmark {
+		return true
+	}
+	if userAllowed != nil && !userAllowed(ss.Namespace) {
+		return false
+	}
+	return !filtered || h.namespaceAllowed(ctx, ss.Namespace) == nil
+}
+
+// writeEvent writes the watch event, it returns false if the stream has to be closed.
+func (h *SecretsHandler) writeEvent(ctx context.Context, w io.Writer, ev watch.Event) bool {
+	switch ev.Type {
+	case watch.Added, watch.Modified, watch.Deleted:
+		ss, ok := ev.Object.(*v1alpha1.SealedSecret)
+		if !ok {
+			return true
+		}
+		status, message := sealedSecretStatus(ss)
+		secret := Secret{
+			Namespace: ss.Namespace,
+			Name:      ss.Name,
+			Status:    status,
+			Message:   message,
+		}
+		if ev.Type != watch.Deleted {
+			secret.SecretExists, _ = h.secretExists(ctx, ss.Namespace, ss.Name)
+		}
+		data, _ := json.Marshal(secret)
+		writeSSE(w, ss.ResourceVersion, strings.ToLower(string(ev.Type)), string(data))
+	case watch.Bookmark:
+		if ss, ok := ev.Object.(*v1alpha1.SealedSecret); ok {
+			// only moves the last event id forward
+			writeSSE(w, ss.ResourceVersion, "", "")
+		}
+	case watch.Error:
+		err := apierrors.FromObject(ev.Object)
+		data, _ := json.Marshal(gin.H{"error": err.Error()})
+		if apierrors.IsResourceExpired(err) || apierrors.IsGone(err) {
+			writeSSE(w, "", "expired", string(data))
+		} else {
+			writeSSE(w, "", "error", string(data))
+		}
+		return false
+	}
+	return true
+}
+
+func writeSSE(w io.Writer, id, event, data string) {
+	var b strings.Builder
+	if id != "" {
+		_, _ = fmt.Fprintf(&b, "id: %s\n", id)
+	}
+	if event != "" {
+		_, _ = fmt.Fprintf(&b, "event: %s\n", event)
+	}
+	if data != "" {
+		_, _ = fmt.Fprintf(&b, "data: %s\n", data)
+	}
+	b.WriteString("\n")
+	_, _ = io.WriteString(w, b.String())
+}
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	ssfake "github.com/bitnami-labs/sealed-secrets/pkg/client/clientset/versioned/fake"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"github.com/gin-gonic/gin"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	v1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/kubernetes/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+var _ = Describe("Handler ", func() {
+	Context("Watch", func() {
+		var (
+			ssClient *ssfake.Clientset
+			h        *SecretsHandler
+			server   *httptest.Server
+		)
+		// connect opens the event stream and returns a function reading the next event or comment
+		connect := func(header http.Header) func() string {
+			req, err := http.NewRequest("GET", server.URL+"/api/secrets/watch", nil)
+			Ω(err).ShouldNot(HaveOccurred())
+			for k, v := range header {
+				req.Header[k] = v
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			DeferCleanup(cancel)
+			resp, err := http.DefaultClient.Do(req.WithContext(ctx))
+			Ω(err).ShouldNot(HaveOccurred())
+			DeferCleanup(resp.Body.Close)
+			Ω(resp.StatusCode).Should(Equal(http.StatusOK))
+			Ω(resp.Header.Get("Content-Type")).Should(Equal("text/event-stream"))
+
+			reader := bufio.NewReader(resp.Body)
+			return func() string {
+				var event []string
+				for {
+					line, err := reader.ReadString('\n')
+					if err != nil {
+						return strings.Join(append(event, "EOF"), "\n")
+					}
+					line = strings.TrimSuffix(line, "\n")
+					if line == "" {
+						return strings.Join(event, "\n")
+					}
+					event = append(event, line)
+				}
+			}
+		}
+
+		// serve serves the watch of a new handler with the config
+		serve := func(cfg *config.Config) {
+			coreClient := fake.NewSimpleClientset(&v1.Secret{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "one"}})
+			metaClient := newMetadataClient(secretMetadata("a", "one"))
+			h = NewHandler(coreClient.CoreV1(), ssClient.BitnamiV1alpha1(), metaClient, cfg)
+
+			r := gin.New()
+			r.GET("/api/secrets/watch", h.Watch)
+			server = httptest.NewServer(r)
+			DeferCleanup(server.Close)
+		}
+
+		BeforeEach(func() {
+			gin.SetMode(gin.ReleaseMode)
+			ssClient = ssfake.NewSimpleClientset()
+			serve(&config.Config{})
+		})
+
+		It("should stream the sealed secret changes", func() {
+			next := connect(nil)
+
+			ss := &v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "a", Name: "one", ResourceVersion: "1"}}
+			_, err := ssClient.BitnamiV1alpha1().SealedSecrets("a").Create(context.Background(), ss, metav1.CreateOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(next()).Should(Equal("id: 1\nevent: added\n" +
+				`data: {"namespace":"a","name":"one","status":"pending","secretExists":true}`))
+
+			ss.ResourceVersion = "2"
+			ss.Status = &v1alpha1.SealedSecretStatus{Conditions: []v1alpha1.SealedSecretCondition{
+				{Type: v1alpha1.SealedSecretSynced, Status: v1.ConditionFalse, Message: "no key could decrypt secret"},
+			}}
+			_, err = ssClient.BitnamiV1alpha1().SealedSecrets("a").Update(context.Background(), ss, metav1.UpdateOptions{})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(next()).Should(Equal("id: 2\nevent: modified\n" +
+				`data: {"namespace":"a","name":"one","status":"failed","message":"no key could decrypt secret","secretExists":true}`))
+
+			Ω(ssClient.BitnamiV1alpha1().SealedSecrets("a").Delete(context.Background(), "one", metav1.DeleteOptions{})).
+				Should(Succeed())
+			Ω(next()).Should(HavePrefix("id: 2\nevent: deleted\n"))
+		})
+
+		It("should send heartbeats", func() {
+			h.heartbeat = 10 * time.Millisecond
+			next := connect(nil)
+
+			Ω(next()).Should(Equal(": heartbeat"))
+		})
+
+		It("should resume from the last event id", func() {
+			fw := watch.NewFake()
+			var resourceVersion string
+			ssClient.PrependWatchReactor("sealedsecrets", func(action k8stesting.Action) (bool, watch.Interface, error) {
+				resourceVersion = action.(k8stesting.WatchActionImpl).WatchRestrictions.ResourceVersion
+				return true, fw, nil
+			})
+
+			next := connect(http.Header{"Last-Event-ID": []string{"42"}})
+			Ω(resourceVersion).Should(Equal("42"))
+
+			go fw.Action(watch.Bookmark, &v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{ResourceVersion: "43"}})
+			Ω(next()).Should(Equal("id: 43"))
+		})
+
+		Context("with several included namespaces", func() {
+			var watched []string
+			BeforeEach(func() {
+				watched = nil
+				ssClient.PrependWatchReactor("sealedsecrets", func(action k8stesting.Action) (bool, watch.Interface, error) {
+					watched = append(watched, action.GetNamespace())
+					return false, nil, nil
+				})
+				serve(&config.Config{IncludeNamespaces: []string{"a", "b"}})
+			})
+
+			It("should watch all namespaces at once and filter the events", func() {
+				next := connect(nil)
+				Ω(watched).Should(Equal([]string{""}))
+
+				for _, ss := range []*v1alpha1.SealedSecret{
+					{ObjectMeta: metav1.ObjectMeta{Namespace: "c", Name: "other", ResourceVersion: "1"}},
+					{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "two", ResourceVersion: "2"}},
+				} {
+					_, err := ssClient.BitnamiV1alpha1().SealedSecrets(ss.Namespace).Create(context.Background(), ss, metav1.CreateOptions{})
+					Ω(err).ShouldNot(HaveOccurred())
+				}
+				Ω(next()).Should(Equal("id: 2\nevent: added\n" +
+					`data: {"namespace":"b","name":"two","status":"pending","secretExists":false}`))
+			})
+
+			Context("without permission to watch all namespaces", func() {
+				BeforeEach(func() {
+					ssClient.PrependWatchReactor("sealedsecrets", func(action k8stesting.Action) (bool, watch.Interface, error) {
+						if action.GetNamespace() == "" {
+							watched = append(watched, "")
+							return true, nil, apierrors.NewForbidden(v1alpha1.Resource("sealedsecrets"), "", errors.New("denied"))
+						}
+						return false, nil, nil
+					})
+				})
+
+				It("should watch the namespaces separately", func() {
+					next := connect(nil)
+					Ω(watched).Should(Equal([]string{"", "a", "b"}))
+
+					ss := &v1alpha1.SealedSecret{ObjectMeta: metav1.ObjectMeta{Namespace: "b", Name: "two", ResourceVersion: "2"}}
+					_, err := ssClient.BitnamiV1alpha1().SealedSecrets("b").Create(context.Background(), ss, metav1.CreateOptions{})
+					Ω(err).ShouldNot(HaveOccurred())
+					Ω(next()).Should(HavePrefix("id: 2\nevent: added\n"))
+				})
+
+				It("should tell a reconnecting client to reload the secrets", func() {
+					next := connect(http.Header{"Last-Event-ID": []string{"42"}})
+
+					Ω(next()).Should(HavePrefix("event: expired\n"))
+					Ω(next()).Should(Equal("EOF"))
+					Ω(watched).Should(Equal([]string{""}))
+				})
+			})
+		})
+
+		It("should tell the client if the resource version expired", func() {
+			fw := watch.NewFake()
+			ssClient.PrependWatchReactor("sealedsecrets", func(k8stesting.Action) (bool, watch.Interface, error) {
+				return true, fw, nil
+			})
+			next := connect(nil)
+
+			go fw.Error(&metav1.Status{
+				Status:  metav1.StatusFailure,
+				Reason:  metav1.StatusReasonExpired,
+				Code:    http.StatusGone,
+				Message: "too old resource version",
+			})
+			Ω(next()).Should(Equal("event: expired\n" + `data: {"error":"too old resource version"}`))
+			Ω(next()).Should(Equal("EOF"))
+		})
+	})
+})
@@ -1,9 +1,9 @@
 // Code generated by MockGen. DO NOT EDIT.
-// Source: github.com/gattma/sealed-secrets-web/pkg/seal (interfaces: Sealer)
+// Source: github.com/gattma/sealed-secrets-web/pkg/seal (interfaces: Sealer,Unsealer)
 //
 // Generated by this command:
 //
-//	mockgen -destination pkg/mocks/seal/mock.go --package seal github.com/gattma/sealed-secrets-web/pkg/seal Sealer
+//	mockgen -destination pkg/mocks/seal/mock.go --package seal github.com/gattma/sealed-secrets-web/pkg/seal Sealer,Unsealer
 //
 
 // Package seal is a generated GoMock package.
@@ -14,8 +14,10 @@ import (
 	io "io"
 	reflect "reflect"
 
+	v1alpha1 "github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
 	seal "github.com/gattma/sealed-secrets-web/pkg/seal"
 	gomock "go.uber.org/mock/gomock"
+	v1 "k8s.io/api/core/v1"
 )
 
 // MockSealer is a mock of Sealer interface.
@@ -57,6 +59,51 @@ func (mr *MockSealerMockRecorder) Certificate(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Certificate", reflect.TypeOf((*MockSealer)(nil).Certificate), ctx)
 }
 
+// CertificateInfo mocks base method.
+func (m *MockSealer) CertificateInfo(ctx context.Context) (*seal.CertificateInfo, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CertificateInfo", ctx)
+	ret0, _ := ret[0].(*seal.CertificateInfo)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CertificateInfo indicates an expected call of CertificateInfo.
+func (mr *MockSealerMockRecorder) CertificateInfo(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CertificateInfo", reflect.TypeOf((*MockSealer)(nil).CertificateInfo), ctx)
+}
+
+// DefaultNamespace mocks base method.
+func (m *MockSealer) DefaultNamespace() (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DefaultNamespace")
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DefaultNamespace indicates an expected call of DefaultNamespace.
+func (mr *MockSealerMockRecorder) DefaultNamespace() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DefaultNamespace", reflect.TypeOf((*MockSealer)(nil).DefaultNamespace))
+}
+
+// Merge mocks base method.
+func (m *MockSealer) Merge(outputFormat string, data seal.Merge) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Merge", outputFormat, data)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Merge indicates an expected call of Merge.
+func (mr *MockSealerMockRecorder) Merge(outputFormat, data any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Merge", reflect.TypeOf((*MockSealer)(nil).Merge), outputFormat, data)
+}
+
 // Raw mocks base method.
 func (m *MockSealer) Raw(data seal.Raw) ([]byte, error) {
 	m.ctrl.T.Helper()
@@ -72,19 +119,34 @@ func (mr *MockSealerMockRecorder) Raw(data any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Raw", reflect.TypeOf((*MockSealer)(nil).Raw), data)
 }
 
+// Rotate mocks base method.
+func (m *MockSealer) Rotate(ctx context.Context, outputFormat string, sealedSecret io.Reader) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Rotate", ctx, outputFormat, sealedSecret)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Rotate indicates an expected call of Rotate.
+func (mr *MockSealerMockRecorder) Rotate(ctx, outputFormat, sealedSecret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Rotate", reflect.TypeOf((*MockSealer)(nil).Rotate), ctx, outputFormat, sealedSecret)
+}
+
 // Seal mocks base method.
-func (m *MockSealer) Seal(outputFormat string, secret io.Reader) ([]byte, error) {
+func (m *MockSealer) Seal(outputFormat string, scope v1alpha1.SealingScope, secret io.Reader) ([]byte, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Seal", outputFormat, secret)
+	ret := m.ctrl.Call(m, "Seal", outputFormat, scope, secret)
 	ret0, _ := ret[0].([]byte)
 	ret1, _ := ret[1].(error)
 	return ret0, ret1
 }
 
 // Seal indicates an expected call of Seal.
-func (mr *MockSealerMockRecorder) Seal(outputFormat, secret any) *gomock.Call {
+func (mr *MockSealerMockRecorder) Seal(outputFormat, scope, secret any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Seal", reflect.TypeOf((*MockSealer)(nil).Seal), outputFormat, secret)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Seal", reflect.TypeOf((*MockSealer)(nil).Seal), outputFormat, scope, secret)
 }
 
 // Validate mocks base method.
@@ -100,3 +162,42 @@ func (mr *MockSealerMockRecorder) Validate(ctx, secret any) *gomock.Call {
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Validate", reflect.TypeOf((*MockSealer)(nil).Validate), ctx, secret)
 }
+
+// MockUnsealer is a mock of Unsealer interface.
+type MockUnsealer struct {
+	ctrl     *gomock.Controller
+	recorder *MockUnsealerMockRecorder
+	isgomock struct{}
+}
+
+// MockUnsealerMockRecorder is the mock recorder for MockUnsealer.
+type MockUnsealerMockRecorder struct {
+	mock *MockUnsealer
+}
+
+// NewMockUnsealer creates a new mock instance.
+func NewMockUnsealer(ctrl *gomock.Controller) *MockUnsealer {
+	mock := &MockUnsealer{ctrl: ctrl}
+	mock.recorder = &MockUnsealerMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockUnsealer) EXPECT() *MockUnsealerMockRecorder {
+	return m.recorder
+}
+
+// Unseal mocks base method.
+func (m *MockUnsealer) Unseal(sealedSecret io.Reader) (*v1.Secret, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Unseal", sealedSecret)
+	ret0, _ := ret[0].(*v1.Secret)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Unseal indicates an expected call of Unseal.
+func (mr *MockUnsealerMockRecorder) Unseal(sealedSecret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Unseal", reflect.TypeOf((*MockUnsealer)(nil).Unseal), sealedSecret)
+}
@@ -4,58 +4,144 @@ import (
 	"bytes"
 	"context"
 	"crypto/rsa"
+	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"log"
 	"os"
+	"strings"
+	"sync/atomic"
+	"time"
 
 	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
 	"github.com/bitnami-labs/sealed-secrets/pkg/kubeseal"
+	"github.com/bitnami-labs/sealed-secrets/pkg/multidocyaml"
 	"github.com/gattma/sealed-secrets-web/pkg/config"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
 	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/client-go/util/cert"
 )
 
 type Sealer interface {
 	Raw(data Raw) ([]byte, error)
 	Certificate(ctx context.Context) ([]byte, error)
-	Seal(outputFormat string, secret io.Reader) ([]byte, error)
+	CertificateInfo(ctx context.Context) (*CertificateInfo, error)
+	Seal(outputFormat string, scope v1alpha1.SealingScope, secret io.Reader) ([]byte, error)
+	Merge(outputFormat string, data Merge) ([]byte, error)
 	Validate(ctx context.Context, secret io.Reader) error
+	Rotate(ctx context.Context, outputFormat string, sealedSecret io.Reader) ([]byte, error)
+	DefaultNamespace() (string, error)
 }
 
 var _ Sealer = &apiSealer{}
 
+// NewClientConfig creates the client config for the given kube context.
+// If the context is empty, the current context is used.
+func NewClientConfig(kubeContext string) clientcmd.ClientConfig {
+	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
+	loadingRules.DefaultClientConfig = &clientcmd.DefaultClientConfig
+	overrides := &clientcmd.ConfigOverrides{CurrentContext: kubeContext}
+	return clientcmd.NewInteractiveDeferredLoadingClientConfig(loadingRules, overrides, os.Stdout)
+}
+
+// NewAPISealers creates a sealer for each of the given controllers, mapped by the controller name.
+func NewAPISealers(ctx context.Context, controllers []config.Controller) (map[string]Sealer, error) {
+	sealers := make(map[string]Sealer, len(controllers))
+	for _, c := range controllers {
+		s, err := NewAPISealer(ctx, c.SealedSecrets)
+		if err != nil {
+			return nil, fmt.Errorf("controller '%s': %w", c.Name, err)
+		}
+		sealers[c.Name] = s
+	}
+	return sealers, nil
+}
+
 func NewAPISealer(ctx context.Context, ss config.SealedSecrets) (Sealer, error) {
 	log.Printf("Connection to sealed secrets with (%s)\n", ss.String())
 
-	loadingRules := clientcmd.NewDefaultClientConfigLoadingRules()
-	loadingRules.DefaultClientConfig = &clientcmd.DefaultClientConfig
-	cc := clientcmd.NewInteractiveDeferredLoadingClientConfig(loadingRules, nil, os.Stdout)
+	a := &apiSealer{ss: ss}
+	if ss.Offline() {
+		a.clientConfig = offlineClientConfig{}
+	} else {
+		a.clientConfig = NewClientConfig(ss.KubeContext)
+	}
+	if err := a.refreshKey(ctx); err != nil {
+		return nil, err
+	}
+	if interval := ss.RefreshInterval(); interval > 0 {
+		go a.refreshPeriodically(ctx, interval)
+	}
+
+	return a, nil
+}
+
+type apiSealer struct {
+	clientConfig kubeseal.ClientConfig
+	ss           config.SealedSecrets
+	pubKey       atomic.Pointer[rsa.PublicKey]
+}
+
+// openCert opens the inline certificate, the certificate file or the certificate of the controller.
+func (a *apiSealer) openCert(ctx context.Context) (io.ReadCloser, error) {
+	if a.ss.Cert != "" {
+		return io.NopCloser(strings.NewReader(a.ss.Cert)), nil
+	}
+	if a.ss.CertFile != "" {
+		return os.Open(a.ss.CertFile)
+	}
+	return kubeseal.OpenCert(ctx, a.clientConfig, a.ss.Namespace, a.ss.Service, a.ss.CertURL)
+}
 
-	f, err := kubeseal.OpenCert(ctx, cc, ss.Namespace, ss.Service, ss.CertURL)
+// refreshKey fetches the current certificate of the controller and replaces the public key used for sealing.
+func (a *apiSealer) refreshKey(ctx context.Context) error {
+	f, err := a.openCert(ctx)
 	if err != nil {
-		return nil, err
+		return err
 	}
 	defer func() { _ = f.Close() }()
 	pubKey, err := kubeseal.ParseKey(f)
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	return &apiSealer{
-		clientConfig: cc,
-		ss:           ss,
-		pubKey:       pubKey,
-	}, nil
+	if old := a.pubKey.Swap(pubKey); old == nil || !old.Equal(pubKey) {
+		fingerprint, _ := crypto.PublicKeyFingerprint(pubKey)
+		if old == nil {
+			log.Printf("Using sealing key %s\n", fingerprint)
+		} else {
+			oldFingerprint, _ := crypto.PublicKeyFingerprint(old)
+			log.Printf("Sealing key changed from %s to %s\n", oldFingerprint, fingerprint)
+		}
+	}
+	return nil
 }
 
-type apiSealer struct {
-	clientConfig clientcmd.ClientConfig
-	ss           config.SealedSecrets
-	pubKey       *rsa.PublicKey
+// refreshPeriodically refreshes the public key in the given interval until the context is done.
+// If the certificate can not be fetched, the last known key is kept.
+func (a *apiSealer) refreshPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := a.refreshKey(ctx); err != nil {
+				log.Printf("Could not refresh the certificate (%s), keeping the current key: %v\n", a.ss.String(), err)
+			}
+		}
+	}
 }
 
 func (a *apiSealer) Certificate(ctx context.Context) ([]byte, error) {
-	f, err := kubeseal.OpenCert(ctx, a.clientConfig, a.ss.Namespace, a.ss.Service, a.ss.CertURL)
+	f, err := a.openCert(ctx)
 	if err != nil {
 		return nil, err
 	}
@@ -68,7 +154,47 @@ func (a *apiSealer) Certificate(ctx context.Context) ([]byte, error) {
 	return data, nil
 }
 
-func (a *apiSealer) Seal(outputFormat string, secret io.Reader) ([]byte, error) {
+// CertificateInfo fetches the current certificate of the controller and compares it with the key used for sealing.
+func (a *apiSealer) CertificateInfo(ctx context.Context) (*CertificateInfo, error) {
+	data, err := a.Certificate(ctx)
+	if err != nil {
+		return nil, err
+	}
+	certs, err := cert.ParseCertsPEM(data)
+	if err != nil {
+		return nil, err
+	}
+	c := certs[0]
+	pubKey, ok := c.PublicKey.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("expected RSA public key but found %T", c.PublicKey)
+	}
+
+	info := &CertificateInfo{
+		Subject:         c.Subject.String(),
+		Issuer:          c.Issuer.String(),
+		SerialNumber:    c.SerialNumber.String(),
+		NotBefore:       c.NotBefore,
+		NotAfter:        c.NotAfter,
+		KeySize:         pubKey.N.BitLen(),
+		DaysUntilExpiry: int(time.Until(c.NotAfter).Hours() / 24),
+	}
+	info.ExpiresSoon = info.DaysUntilExpiry < expiryWarningDays
+	if info.Fingerprint, err = crypto.PublicKeyFingerprint(pubKey); err != nil {
+		return nil, err
+	}
+	if sealingKey := a.pubKey.Load(); sealingKey != nil {
+		if info.SealingKeyFingerprint, err = crypto.PublicKeyFingerprint(sealingKey); err != nil {
+			return nil, err
+		}
+		info.SealingKeyCurrent = sealingKey.Equal(pubKey)
+	}
+	return info, nil
+}
+
+// Seal seals the given secret with the requested scope.
+// With the default scope, the scope annotations of the input secret are honored.
+func (a *apiSealer) Seal(outputFormat string, scope v1alpha1.SealingScope, secret io.Reader) ([]byte, error) {
 	var buf bytes.Buffer
 	if err := kubeseal.Seal(
 		a.clientConfig,
@@ -76,8 +202,8 @@ func (a *apiSealer) Seal(outputFormat string, secret io.Reader) ([]byte, error)
 		secret,
 		&buf,
 		scheme.Codecs,
-		a.pubKey,
-		v1alpha1.DefaultScope,
+		a.pubKey.Load(),
+		scope,
 		false,
 		"",
 		"",
@@ -87,6 +213,79 @@ func (a *apiSealer) Seal(outputFormat string, secret io.Reader) ([]byte, error)
 	return buf.Bytes(), nil
 }
 
+// Merge seals the given secret and merges the result into the given sealed secret like kubeseal --merge-into.
+// Only the keys of the secret are re-encrypted, all other encrypted data is kept as it is.
+func (a *apiSealer) Merge(outputFormat string, data Merge) ([]byte, error) {
+	orig, err := decodeSealedSecret([]byte(data.SealedSecret))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealed secret: %w", err)
+	}
+	target, err := mergeTarget([]byte(data.SealedSecret))
+	if err != nil {
+		return nil, fmt.Errorf("invalid sealed secret: %w", err)
+	}
+
+	// kubeseal merges into a file
+	f, err := os.CreateTemp("", "sealed-secret-*.json")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	_, err = f.Write(target)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if err := kubeseal.SealMergingInto(
+		a.clientConfig,
+		outputFormat,
+		strings.NewReader(data.Secret),
+		f.Name(),
+		scheme.Codecs,
+		a.pubKey.Load(),
+		orig.Scope(),
+		false,
+	); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(f.Name())
+}
+
+// mergeTarget converts the sealed secret to json with empty maps for the missing data, labels and annotations,
+// kubeseal adds the merged keys to these maps without creating them.
+func mergeTarget(sealedSecret []byte) ([]byte, error) {
+	b, err := yaml.ToJSON(sealedSecret)
+	if err != nil {
+		return nil, err
+	}
+	var obj map[string]interface{}
+	if err := json.Unmarshal(b, &obj); err != nil {
+		return nil, err
+	}
+	for _, path := range [][]string{
+		{"spec", "encryptedData"},
+		{"spec", "template", "data"},
+		{"spec", "template", "metadata", "labels"},
+		{"spec", "template", "metadata", "annotations"},
+	} {
+		if _, found, _ := unstructured.NestedFieldNoCopy(obj, path...); !found {
+			if err := unstructured.SetNestedMap(obj, map[string]interface{}{}, path...); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return json.Marshal(obj)
+}
+
+// DefaultNamespace returns the namespace of the kube context, kubeseal seals secrets without a namespace for it.
+func (a *apiSealer) DefaultNamespace() (string, error) {
+	ns, _, err := a.clientConfig.Namespace()
+	return ns, err
+}
+
 func (a *apiSealer) Raw(data Raw) ([]byte, error) {
 	var buf bytes.Buffer
 	scope := v1alpha1.DefaultScope
@@ -95,13 +294,16 @@ func (a *apiSealer) Raw(data Raw) ([]byte, error) {
 	}
 	if err := kubeseal.EncryptSecretItem(
 		&buf, data.Name, data.Namespace, []byte(data.Value),
-		scope, a.pubKey); err != nil {
+		scope, a.pubKey.Load()); err != nil {
 		return nil, err
 	}
 	return buf.Bytes(), nil
 }
 
 func (a *apiSealer) Validate(ctx context.Context, secret io.Reader) error {
+	if a.ss.Offline() {
+		return ErrOffline
+	}
 	return kubeseal.ValidateSealedSecret(
 		ctx,
 		a.clientConfig,
@@ -111,9 +313,84 @@ func (a *apiSealer) Validate(ctx context.Context, secret io.Reader) error {
 	)
 }
 
+// Rotate re-encrypts the given sealed secrets with the latest key of the controller.
+func (a *apiSealer) Rotate(ctx context.Context, outputFormat string, sealedSecret io.Reader) ([]byte, error) {
+	if a.ss.Offline() {
+		return nil, ErrOffline
+	}
+	var buf bytes.Buffer
+	if err := kubeseal.ReEncryptSealedSecret(
+		ctx,
+		a.clientConfig,
+		a.ss.Namespace,
+		a.ss.Service,
+		outputFormat,
+		sealedSecret,
+		&buf,
+		scheme.Codecs,
+	); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeSealedSecret(b []byte) (*v1alpha1.SealedSecret, error) {
+	if err := multidocyaml.EnsureNotMultiDoc(b); err != nil {
+		return nil, err
+	}
+	var ss v1alpha1.SealedSecret
+	if err := runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), b, &ss); err != nil {
+		return nil, err
+	}
+	return &ss, nil
+}
+
+// ErrOffline is returned by operations that need access to the controller when sealing offline.
+var ErrOffline = errors.New("not available in offline mode")
+
+// ErrNamespaceRequired is returned for secrets without namespace when sealing offline,
+// as there is no kube context to take the default namespace from.
+var ErrNamespaceRequired = errors.New("namespace is required when sealing offline")
+
+// offlineClientConfig is used when sealing offline, it behaves like an empty kube config without default namespace.
+type offlineClientConfig struct{}
+
+func (offlineClientConfig) ClientConfig() (*rest.Config, error) {
+	return nil, ErrOffline
+}
+
+func (offlineClientConfig) Namespace() (string, bool, error) {
+	return "", false, ErrNamespaceRequired
+}
+
+// expiryWarningDays is the number of days before the certificate expiry a warning is shown.
+const expiryWarningDays = 30
+
+// CertificateInfo describes the current certificate of the controller.
+type CertificateInfo struct {
+	Subject         string    `json:"subject"         yaml:"subject"`
+	Issuer          string    `json:"issuer"          yaml:"issuer"`
+	SerialNumber    string    `json:"serialNumber"    yaml:"serialNumber"`
+	NotBefore       time.Time `json:"notBefore"       yaml:"notBefore"`
+	NotAfter        time.Time `json:"notAfter"        yaml:"notAfter"`
+	Fingerprint     string    `json:"fingerprint"     yaml:"fingerprint"`
+	KeySize         int       `json:"keySize"         yaml:"keySize"`
+	DaysUntilExpiry int       `json:"daysUntilExpiry" yaml:"daysUntilExpiry"`
+	ExpiresSoon     bool      `json:"expiresSoon"     yaml:"expiresSoon"`
+	// SealingKeyFingerprint is the fingerprint of the key currently used for sealing.
+	SealingKeyFingerprint string `json:"sealingKeyFingerprint" yaml:"sealingKeyFingerprint"`
+	// SealingKeyCurrent is true if the key used for sealing is the current key of the controller.
+	SealingKeyCurrent bool `json:"sealingKeyCurrent" yaml:"sealingKeyCurrent"`
+}
+
 type Raw struct {
 	Value     string `json:"value"`
 	Name      string `json:"name"`
 	Namespace string `json:"namespace"`
 	Scope     string `json:"scope"`
 }
+
+type Merge struct {
+	SealedSecret string `json:"sealedSecret"`
+	Secret       string `json:"secret"`
+}
@@ -0,0 +1,327 @@
+package seal
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	"github.com/gattma/sealed-secrets-web/pkg/config"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"k8s.io/client-go/kubernetes/scheme"
+)
+
+var _ = Describe("Seal", func() {
+	Context("certificate refresh", func() {
+		var (
+			certs  *certServer
+			ctx    context.Context
+			cancel context.CancelFunc
+		)
+		BeforeEach(func() {
+			ctx, cancel = context.WithCancel(context.Background())
+			certs = newCertServer()
+		})
+		AfterEach(func() {
+			cancel()
+			certs.Close()
+		})
+
+		It("should load the key from the cert url", func() {
+			s, err := NewAPISealer(ctx, config.SealedSecrets{CertURL: certs.URL})
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(s.(*apiSealer).pubKey.Load().Equal(&certs.key().PublicKey)).Should(BeTrue())
+
+			cert, err := s.Certificate(ctx)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cert).Should(Equal(certs.pem()))
+		})
+
+		It("should fail if the certificate can not be fetched", func() {
+			certs.fail.Store(true)
+			_, err := NewAPISealer(ctx, config.SealedSecrets{CertURL: certs.URL})
+			Ω(err).Should(HaveOccurred())
+		})
+
+		It("should pick up a rotated key", func() {
+			s, err := NewAPISealer(ctx, config.SealedSecrets{CertURL: certs.URL})
+			Ω(err).ShouldNot(HaveOccurred())
+			a := s.(*apiSealer)
+
+			certs.rotate()
+			Ω(a.pubKey.Load().Equal(&certs.key().PublicKey)).Should(BeFalse())
+			Ω(a.refreshKey(ctx)).Should(Succeed())
+			Ω(a.pubKey.Load().Equal(&certs.key().PublicKey)).Should(BeTrue())
+		})
+
+		It("should keep the last key if the controller is not reachable", func() {
+			s, err := NewAPISealer(ctx, config.SealedSecrets{CertURL: certs.URL})
+			Ω(err).ShouldNot(HaveOccurred())
+			a := s.(*apiSealer)
+
+			certs.fail.Store(true)
+			Ω(a.refreshKey(ctx)).ShouldNot(Succeed())
+			Ω(a.pubKey.Load().Equal(&certs.key().PublicKey)).Should(BeTrue())
+		})
+
+		It("should return the certificate info", func() {
+			s, err := NewAPISealer(ctx, config.SealedSecrets{CertURL: certs.URL})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			info, err := s.CertificateInfo(ctx)
+			Ω(err).ShouldNot(HaveOccurred())
+			fingerprint, _ := crypto.PublicKeyFingerprint(&certs.key().PublicKey)
+			Ω(info.Subject).Should(Equal("CN=sealed-secrets-web"))
+			Ω(info.Issuer).Should(Equal("CN=sealed-secrets-web"))
+			Ω(info.SerialNumber).ShouldNot(BeEmpty())
+			Ω(info.KeySize).Should(Equal(2048))
+			Ω(info.Fingerprint).Should(Equal(fingerprint))
+			Ω(info.SealingKeyFingerprint).Should(Equal(fingerprint))
+			Ω(info.SealingKeyCurrent).Should(BeTrue())
+			Ω(info.DaysUntilExpiry).Should(Equal(0))
+			Ω(info.ExpiresSoon).Should(BeTrue())
+		})
+
+		It("should report a sealing key that is not current", func() {
+			s, err := NewAPISealer(ctx, config.SealedSecrets{CertURL: certs.URL})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			certs.rotate()
+			info, err := s.CertificateInfo(ctx)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(info.SealingKeyCurrent).Should(BeFalse())
+			Ω(info.SealingKeyFingerprint).ShouldNot(Equal(info.Fingerprint))
+		})
+
+		It("should refresh the key periodically", func() {
+			interval := 10 * time.Millisecond
+			s, err := NewAPISealer(ctx, config.SealedSecrets{
+				CertURL:             certs.URL,
+				CertRefreshInterval: &interval,
+			})
+			Ω(err).ShouldNot(HaveOccurred())
+			a := s.(*apiSealer)
+
+			certs.rotate()
+			Eventually(func() bool {
+				return a.pubKey.Load().Equal(&certs.key().PublicKey)
+			}).Should(BeTrue())
+		})
+	})
+
+	var (
+		key    *rsa.PrivateKey
+		sealer *apiSealer
+	)
+	BeforeEach(func() {
+		var err error
+		key, _, err = crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "sealed-secrets-web")
+		Ω(err).ShouldNot(HaveOccurred())
+		sealer = &apiSealer{}
+		sealer.pubKey.Store(&key.PublicKey)
+	})
+
+	unseal := func(b []byte) map[string]string {
+		ss, err := decodeSealedSecret(b)
+		Ω(err).ShouldNot(HaveOccurred())
+		sec, err := ss.Unseal(scheme.Codecs, map[string]*rsa.PrivateKey{"key": key})
+		Ω(err).ShouldNot(HaveOccurred())
+		data := map[string]string{}
+		for k, v := range sec.Data {
+			data[k] = string(v)
+		}
+		return data
+	}
+
+	Context("offline", func() {
+		var certFile string
+		BeforeEach(func() {
+			k, c, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "sealed-secrets-web")
+			Ω(err).ShouldNot(HaveOccurred())
+			key = k
+			certFile = filepath.Join(GinkgoT().TempDir(), "cert.pem")
+			Ω(os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: c.Raw}), 0o600)).
+				Should(Succeed())
+		})
+
+		It("should seal with a certificate file", func() {
+			s, err := NewAPISealer(context.Background(), config.SealedSecrets{CertFile: certFile})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			sealed, err := s.Seal("yaml", v1alpha1.DefaultScope, strings.NewReader(origSecret))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(unseal(sealed)).Should(HaveKeyWithValue("username", "admin"))
+		})
+
+		It("should seal with an inline certificate", func() {
+			b, err := os.ReadFile(certFile)
+			Ω(err).ShouldNot(HaveOccurred())
+			s, err := NewAPISealer(context.Background(), config.SealedSecrets{Cert: string(b)})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			cert, err := s.Certificate(context.Background())
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(cert).Should(Equal(b))
+
+			sealed, err := s.Seal("json", v1alpha1.DefaultScope, strings.NewReader(origSecret))
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(unseal(sealed)).Should(HaveKeyWithValue("password", "secret"))
+		})
+
+		It("should require a namespace", func() {
+			s, err := NewAPISealer(context.Background(), config.SealedSecrets{CertFile: certFile})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = s.Seal("yaml", v1alpha1.DefaultScope, strings.NewReader(partialSecret))
+			Ω(err).Should(MatchError(ErrNamespaceRequired))
+
+			_, err = s.DefaultNamespace()
+			Ω(err).Should(MatchError(ErrNamespaceRequired))
+		})
+
+		It("should not validate", func() {
+			s, err := NewAPISealer(context.Background(), config.SealedSecrets{CertFile: certFile})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			Ω(s.Validate(context.Background(), strings.NewReader(""))).Should(MatchError(ErrOffline))
+		})
+
+		It("should not re-encrypt", func() {
+			s, err := NewAPISealer(context.Background(), config.SealedSecrets{CertFile: certFile})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			_, err = s.Rotate(context.Background(), "yaml", strings.NewReader(""))
+			Ω(err).Should(MatchError(ErrOffline))
+		})
+	})
+
+	Context("Merge", func() {
+		var orig []byte
+		BeforeEach(func() {
+			var err error
+			orig, err = sealer.Seal("yaml", v1alpha1.DefaultScope, strings.NewReader(origSecret))
+			Ω(err).ShouldNot(HaveOccurred())
+		})
+
+		It("should only re-encrypt the given keys", func() {
+			merged, err := sealer.Merge("yaml", Merge{SealedSecret: string(orig), Secret: partialSecret})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			origSS, _ := decodeSealedSecret(orig)
+			mergedSS, err := decodeSealedSecret(merged)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(mergedSS.Name).Should(Equal("mysecret"))
+			Ω(mergedSS.Namespace).Should(Equal("myns"))
+			Ω(mergedSS.Spec.EncryptedData["username"]).Should(Equal(origSS.Spec.EncryptedData["username"]))
+			Ω(mergedSS.Spec.EncryptedData["password"]).ShouldNot(Equal(origSS.Spec.EncryptedData["password"]))
+
+			Ω(unseal(merged)).Should(Equal(map[string]string{
+				"username": "admin",
+				"password": "changed",
+				"token":    "new",
+			}))
+		})
+
+		It("should keep the scope of the sealed secret", func() {
+			orig, err := sealer.Seal("json", v1alpha1.ClusterWideScope, strings.NewReader(origSecret))
+			Ω(err).ShouldNot(HaveOccurred())
+
+			merged, err := sealer.Merge("json", Merge{SealedSecret: string(orig), Secret: partialSecret})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			mergedSS, err := decodeSealedSecret(merged)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(mergedSS.Scope()).Should(Equal(v1alpha1.ClusterWideScope))
+			Ω(unseal(merged)).Should(HaveKeyWithValue("token", "new"))
+		})
+
+		It("should merge labels into a sealed secret without labels", func() {
+			labeled := strings.Replace(partialSecret, "  name: mysecret\n", "  name: mysecret\n  labels:\n    app: web\n", 1)
+			merged, err := sealer.Merge("yaml", Merge{SealedSecret: string(orig), Secret: labeled})
+			Ω(err).ShouldNot(HaveOccurred())
+
+			mergedSS, err := decodeSealedSecret(merged)
+			Ω(err).ShouldNot(HaveOccurred())
+			Ω(mergedSS.Spec.Template.Labels).Should(Equal(map[string]string{"app": "web"}))
+			Ω(string(merged)).ShouldNot(ContainSubstring("annotations"))
+			Ω(unseal(merged)).Should(HaveKeyWithValue("token", "new"))
+		})
+
+		It("should fail if the sealed secret is invalid", func() {
+			_, err := sealer.Merge("yaml", Merge{SealedSecret: "foo", Secret: partialSecret})
+			Ω(err).Should(HaveOccurred())
+			Ω(err.Error()).Should(HavePrefix("invalid sealed secret"))
+		})
+	})
+})
+
+const (
+	origSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: mysecret
+  namespace: myns
+stringData:
+  username: admin
+  password: secret
+`
+	partialSecret = `apiVersion: v1
+kind: Secret
+metadata:
+  name: mysecret
+stringData:
+  password: changed
+  token: new
+`
+)
+
+// certServer serves a sealed secrets controller certificate that can be rotated.
+type certServer struct {
+	*httptest.Server
+	current atomic.Pointer[certKey]
+	fail    atomic.Bool
+}
+
+type certKey struct {
+	key *rsa.PrivateKey
+	pem []byte
+}
+
+func newCertServer() *certServer {
+	cs := &certServer{}
+	cs.rotate()
+	cs.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		if cs.fail.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		_, _ = w.Write(cs.pem())
+	}))
+	return cs
+}
+
+func (cs *certServer) rotate() {
+	key, cert, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "sealed-secrets-web")
+	Ω(err).ShouldNot(HaveOccurred())
+	cs.current.Store(&certKey{
+		key: key,
+		pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw}),
+	})
+}
+
+func (cs *certServer) key() *rsa.PrivateKey {
+	return cs.current.Load().key
+}
+
+func (cs *certServer) pem() []byte {
+	return cs.current.Load().pem
+}
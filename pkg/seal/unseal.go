@@ -0,0 +1,135 @@
+package seal
+
+import (
+	"bytes"
+	"crypto/rsa"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	v1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/yaml"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/util/keyutil"
+)
+
+type Unsealer interface {
+	Unseal(sealedSecret io.Reader) (*v1.Secret, error)
+}
+
+var _ Unsealer = &keyUnsealer{}
+
+// NewKeyUnsealer creates an unsealer with the private keys of the given file.
+// The file can contain PEM encoded private keys or the sealing key secrets (single, list or multi document)
+// as exported from the controller namespace.
+func NewKeyUnsealer(keyFile string) (Unsealer, error) {
+	b, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := parsePrivateKeys(b)
+	if err != nil {
+		return nil, fmt.Errorf("could not read the private keys from %s: %w", keyFile, err)
+	}
+	return &keyUnsealer{keys: keys}, nil
+}
+
+type keyUnsealer struct {
+	keys map[string]*rsa.PrivateKey
+}
+
+func (u *keyUnsealer) Unseal(sealedSecret io.Reader) (*v1.Secret, error) {
+	b, err := io.ReadAll(sealedSecret)
+	if err != nil {
+		return nil, err
+	}
+	ss, err := decodeSealedSecret(b)
+	if err != nil {
+		return nil, err
+	}
+	return ss.Unseal(scheme.Codecs, u.keys)
+}
+
+// parsePrivateKeys parses PEM encoded private keys or the tls.key of the sealing key secrets.
+// The keys are mapped by the fingerprint of their public key.
+func parsePrivateKeys(b []byte) (map[string]*rsa.PrivateKey, error) {
+	var pems [][]byte
+	if bytes.Contains(b, []byte("-----BEGIN")) {
+		for block, rest := pem.Decode(b); block != nil; block, rest = pem.Decode(rest) {
+			pems = append(pems, pem.EncodeToMemory(block))
+		}
+	} else {
+		secrets, err := readKeySecrets(b)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range secrets {
+			tlsKey, ok := s.Data[v1.TLSPrivateKeyKey]
+			if !ok {
+				return nil, fmt.Errorf("secret %s must contain a '%s' key", s.Name, v1.TLSPrivateKeyKey)
+			}
+			pems = append(pems, tlsKey)
+		}
+	}
+
+	keys := make(map[string]*rsa.PrivateKey)
+	for _, p := range pems {
+		key, err := keyutil.ParsePrivateKeyPEM(p)
+		if err != nil {
+			return nil, err
+		}
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("unexpected private key type %T", key)
+		}
+		fingerprint, err := crypto.PublicKeyFingerprint(&rsaKey.PublicKey)
+		if err != nil {
+			return nil, err
+		}
+		keys[fingerprint] = rsaKey
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("no private keys found")
+	}
+	return keys, nil
+}
+
+// readKeySecrets reads secrets from a single, list or multi document input.
+func readKeySecrets(b []byte) ([]*v1.Secret, error) {
+	var secrets []*v1.Secret
+	decoder := yaml.NewYAMLOrJSONDecoder(bytes.NewReader(b), 4096)
+	for {
+		var raw runtime.RawExtension
+		if err := decoder.Decode(&raw); err != nil {
+			if errors.Is(err, io.EOF) {
+				return secrets, nil
+			}
+			return nil, err
+		}
+		if len(bytes.TrimSpace(raw.Raw)) == 0 || string(raw.Raw) == "null" {
+			continue
+		}
+
+		var list v1.List
+		if err := runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), raw.Raw, &list); err == nil {
+			for _, item := range list.Items {
+				var s v1.Secret
+				if err := runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), item.Raw, &s); err != nil {
+					return nil, err
+				}
+				secrets = append(secrets, &s)
+			}
+			continue
+		}
+
+		var s v1.Secret
+		if err := runtime.DecodeInto(scheme.Codecs.UniversalDecoder(), raw.Raw, &s); err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, &s)
+	}
+}
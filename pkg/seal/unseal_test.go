@@ -0,0 +1,102 @@
+package seal
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bitnami-labs/sealed-secrets/pkg/apis/sealedsecrets/v1alpha1"
+	"github.com/bitnami-labs/sealed-secrets/pkg/crypto"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Unseal", func() {
+	var (
+		key     *rsa.PrivateKey
+		sealed  []byte
+		keyFile string
+	)
+	BeforeEach(func() {
+		var err error
+		key, _, err = crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "sealed-secrets-web")
+		Ω(err).ShouldNot(HaveOccurred())
+		sealer := &apiSealer{}
+		sealer.pubKey.Store(&key.PublicKey)
+		sealed, err = sealer.Seal("yaml", v1alpha1.DefaultScope, strings.NewReader(origSecret))
+		Ω(err).ShouldNot(HaveOccurred())
+		keyFile = filepath.Join(GinkgoT().TempDir(), "keys")
+	})
+
+	keyPEM := func(k *rsa.PrivateKey) []byte {
+		return pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(k)})
+	}
+
+	It("should unseal with a PEM key bundle", func() {
+		other, _, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "other")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(os.WriteFile(keyFile, append(keyPEM(other), keyPEM(key)...), 0o600)).Should(Succeed())
+
+		u, err := NewKeyUnsealer(keyFile)
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(u.(*keyUnsealer).keys).Should(HaveLen(2))
+
+		secret, err := u.Unseal(strings.NewReader(string(sealed)))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(secret.Name).Should(Equal("mysecret"))
+		Ω(secret.Namespace).Should(Equal("myns"))
+		Ω(secret.Data).Should(HaveKeyWithValue("username", []byte("admin")))
+		Ω(secret.Data).Should(HaveKeyWithValue("password", []byte("secret")))
+	})
+
+	It("should unseal with the exported key secrets", func() {
+		Ω(os.WriteFile(keyFile, []byte(fmt.Sprintf(`apiVersion: v1
+kind: List
+items:
+- apiVersion: v1
+  kind: Secret
+  metadata:
+    name: sealed-secrets-key
+  type: kubernetes.io/tls
+  data:
+    tls.key: %s
+`, base64.StdEncoding.EncodeToString(keyPEM(key)))), 0o600)).Should(Succeed())
+
+		u, err := NewKeyUnsealer(keyFile)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		secret, err := u.Unseal(strings.NewReader(string(sealed)))
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(secret.Data).Should(HaveKeyWithValue("username", []byte("admin")))
+	})
+
+	It("should fail if no key can decrypt the secret", func() {
+		other, _, err := crypto.GeneratePrivateKeyAndCert(2048, time.Hour, "other")
+		Ω(err).ShouldNot(HaveOccurred())
+		Ω(os.WriteFile(keyFile, keyPEM(other), 0o600)).Should(Succeed())
+
+		u, err := NewKeyUnsealer(keyFile)
+		Ω(err).ShouldNot(HaveOccurred())
+
+		_, err = u.Unseal(strings.NewReader(string(sealed)))
+		Ω(err).Should(HaveOccurred())
+	})
+
+	It("should fail if the file contains no keys", func() {
+		Ω(os.WriteFile(keyFile, []byte("apiVersion: v1\nkind: List\nitems: []\n"), 0o600)).Should(Succeed())
+
+		_, err := NewKeyUnsealer(keyFile)
+		Ω(err).Should(MatchError(ContainSubstring("no private keys found")))
+	})
+
+	It("should fail if the file does not exist", func() {
+		_, err := NewKeyUnsealer(filepath.Join(GinkgoT().TempDir(), "missing"))
+		Ω(err).Should(HaveOccurred())
+	})
+})
@@ -0,0 +1,273 @@
+package test
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// OIDCClientID is the client id accepted by the mock OIDC provider.
+	OIDCClientID = "sealed-secrets-web"
+	// OIDCClientSecret is the client secret accepted by the mock OIDC provider.
+	OIDCClientSecret = "secret"
+	oidcKeyID        = "test"
+)
+
+// OIDCProvider is a mock OpenID Connect provider issuing signed tokens for tests.
+// The authorization endpoint logs in without user interaction, its codes can be used once and are bound to the
+// PKCE code challenge and the nonce of the request. Issued refresh tokens are rotated on each refresh.
+type OIDCProvider struct {
+	Server *httptest.Server
+	// Claims are added to the id and access tokens
+	Claims map[string]interface{}
+	// TokenLifetime is the lifetime of the issued tokens
+	TokenLifetime time.Duration
+	// DisableEndSession removes the end_session_endpoint from the discovery, it has to be set before the discovery
+	DisableEndSession bool
+
+	key           *rsa.PrivateKey
+	mu            sync.Mutex
+	refreshTokens map[string]bool
+	codes         map[string]authorization
+	tokenRequests []url.Values
+	issued        int
+}
+
+// authorization is the request an authorization code was issued for.
+type authorization struct {
+	codeChallenge       string
+	codeChallengeMethod string
+	nonce               string
+}
+
+// NewOIDCProvider starts a mock OIDC provider, it has to be closed after the test.
+func NewOIDCProvider() *OIDCProvider {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		panic(err)
+	}
+	p := &OIDCProvider{
+		Claims:        map[string]interface{}{"preferred_username": "jane", "groups": []string{"dev"}},
+		TokenLifetime: time.Hour,
+		key:           key,
+		refreshTokens: map[string]bool{},
+		codes:         map[string]authorization{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/.well-known/openid-configuration", p.discovery)
+	mux.HandleFunc("/auth", p.authorize)
+	mux.HandleFunc("/keys", p.keys)
+	mux.HandleFunc("/token", p.token)
+	p.Server = httptest.NewServer(mux)
+	return p
+}
+
+// URL returns the issuer url.
+func (p *OIDCProvider) URL() string {
+	return p.Server.URL
+}
+
+// Close stops the provider.
+func (p *OIDCProvider) Close() {
+	p.Server.Close()
+}
+
+// TokenRequests returns the form values of all token requests.
+func (p *OIDCProvider) TokenRequests() []url.Values {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]url.Values(nil), p.tokenRequests...)
+}
+
+// RefreshToken returns a new valid refresh token.
+func (p *OIDCProvider) RefreshToken() string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.newRefreshToken()
+}
+
+// RevokeRefreshTokens makes all issued refresh tokens invalid.
+func (p *OIDCProvider) RevokeRefreshTokens() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.refreshTokens = map[string]bool{}
+}
+
+// Token returns a signed token of the subject expiring after the given lifetime.
+func (p *OIDCProvider) Token(subject string, lifetime time.Duration) string {
+	return p.sign(p.claims(subject, lifetime))
+}
+
+func (p *OIDCProvider) claims(subject string, lifetime time.Duration) map[string]interface{} {
+	now := time.Now()
+	claims := map[string]interface{}{
+		"iss": p.URL(),
+		"aud": OIDCClientID,
+		"sub": subject,
+		"iat": now.Unix(),
+		"exp": now.Add(lifetime).Unix(),
+	}
+	for k, v := range p.Claims {
+		claims[k] = v
+	}
+	return claims
+}
+
+func (p *OIDCProvider) newRefreshToken() string {
+	p.issued++
+	token := fmt.Sprintf("refresh-%d", p.issued)
+	p.refreshTokens[token] = true
+	return token
+}
+
+func (p *OIDCProvider) sign(claims map[string]interface{}) string {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "kid": oidcKeyID, "typ": "JWT"})
+	payload, _ := json.Marshal(claims)
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		panic(err)
+	}
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature)
+}
+
+// EndSessionEndpoint returns the url of the RP-initiated logout.
+func (p *OIDCProvider) EndSessionEndpoint() string {
+	return p.URL() + "/logout"
+}
+
+func (p *OIDCProvider) discovery(w http.ResponseWriter, _ *http.Request) {
+	metadata := map[string]interface{}{
+		"issuer":                                p.URL(),
+		"authorization_endpoint":                p.URL() + "/auth",
+		"token_endpoint":                        p.URL() + "/token",
+		"jwks_uri":                              p.URL() + "/keys",
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+	}
+	if !p.DisableEndSession {
+		metadata["end_session_endpoint"] = p.EndSessionEndpoint()
+	}
+	writeJSON(w, http.StatusOK, metadata)
+}
+
+// authorize redirects back to the client with a new authorization code, the user is logged in without interaction.
+func (p *OIDCProvider) authorize(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	redirectURL, err := url.Parse(query.Get("redirect_uri"))
+	if err != nil || query.Get("client_id") != OIDCClientID || query.Get("response_type") != "code" {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+
+	p.mu.Lock()
+	p.issued++
+	code := fmt.Sprintf("code-%d", p.issued)
+	p.codes[code] = authorization{
+		codeChallenge:       query.Get("code_challenge"),
+		codeChallengeMethod: query.Get("code_challenge_method"),
+		nonce:               query.Get("nonce"),
+	}
+	p.mu.Unlock()
+
+	params := redirectURL.Query()
+	params.Set("code", code)
+	params.Set("state", query.Get("state"))
+	redirectURL.RawQuery = params.Encode()
+	http.Redirect(w, r, redirectURL.String(), http.StatusFound)
+}
+
+// verifyCodeChallenge checks the PKCE code verifier against the challenge of the authorization request.
+func verifyCodeChallenge(authz authorization, verifier string) bool {
+	switch authz.codeChallengeMethod {
+	case "":
+		return authz.codeChallenge == ""
+	case "plain":
+		return verifier == authz.codeChallenge
+	case "S256":
+		digest := sha256.Sum256([]byte(verifier))
+		return base64.RawURLEncoding.EncodeToString(digest[:]) == authz.codeChallenge
+	default:
+		return false
+	}
+}
+
+func (p *OIDCProvider) keys(w http.ResponseWriter, _ *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"alg": "RS256",
+			"use": "sig",
+			"kid": oidcKeyID,
+			"n":   base64.RawURLEncoding.EncodeToString(p.key.N.Bytes()),
+			"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(p.key.E)).Bytes()),
+		}},
+	})
+}
+
+func (p *OIDCProvider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_request"})
+		return
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.tokenRequests = append(p.tokenRequests, r.PostForm)
+
+	clientID, clientSecret, ok := r.BasicAuth()
+	if !ok {
+		clientID, clientSecret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	if clientID != OIDCClientID || clientSecret != OIDCClientSecret {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_client"})
+		return
+	}
+
+	idClaims := p.claims("subject", p.TokenLifetime)
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		authz, ok := p.codes[r.PostForm.Get("code")]
+		delete(p.codes, r.PostForm.Get("code"))
+		if !ok || !verifyCodeChallenge(authz, r.PostForm.Get("code_verifier")) {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		if authz.nonce != "" {
+			idClaims["nonce"] = authz.nonce
+		}
+	case "refresh_token":
+		if !p.refreshTokens[r.PostForm.Get("refresh_token")] {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "invalid_grant"})
+			return
+		}
+		delete(p.refreshTokens, r.PostForm.Get("refresh_token"))
+	default:
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "unsupported_grant_type"})
+		return
+	}
+
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"access_token":  p.Token("subject", p.TokenLifetime),
+		"id_token":      p.sign(idClaims),
+		"refresh_token": p.newRefreshToken(),
+		"token_type":    "Bearer",
+		"expires_in":    int(p.TokenLifetime.Seconds()),
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
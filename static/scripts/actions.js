@@ -1,6 +1,68 @@
+// withController adds the selected sealed secrets controller to the given api url
+function withController(url) {
+    const select = document.getElementById('controller-select');
+    if (!select || !select.value) {
+        return url;
+    }
+    const separator = url.includes('?') ? '&' : '?';
+    return `${url}${separator}controller=${encodeURIComponent(select.value)}`;
+}
+
+// checkCertificate warns if the sealing key is outdated or the certificate expires soon
+async function checkCertificate() {
+    try {
+        const response = await fetch(withController('/api/certificate/info'), {
+            headers: { 'Accept': 'application/json' }
+        });
+        if (!response.ok) {
+            throw new Error(`HTTP error! Status: ${response.status}`);
+        }
+        const info = await response.json();
+        if (!info.sealingKeyCurrent) {
+            showSnackbar(`The sealing key (${info.sealingKeyFingerprint}) differs from the current controller key (${info.fingerprint}).`, 'warning', 10000);
+        } else if (info.expiresSoon) {
+            showSnackbar(`The controller certificate expires in ${info.daysUntilExpiry} days.`, 'warning', 10000);
+        }
+    } catch (error) {
+        console.error('Error checking certificate:', error);
+    }
+}
+
+let secretsSource = null;
+let secretsRefresh = null;
+
+// watchSecrets refreshes the secrets list on changes of sealed secrets
+function watchSecrets() {
+    stopWatchingSecrets();
+    secretsSource = new EventSource(withController('/api/secrets/watch'));
+    const refresh = () => {
+        clearTimeout(secretsRefresh);
+        secretsRefresh = setTimeout(fetchSecrets, 500);
+    };
+    ['added', 'modified', 'deleted'].forEach(type => secretsSource.addEventListener(type, refresh));
+    secretsSource.addEventListener('expired', () => {
+        // the resource version is outdated, reload the list and watch from now on
+        fetchSecrets();
+        watchSecrets();
+    });
+    secretsSource.addEventListener('error', (e) => {
+        if (e.data) {
+            console.error('Error watching secrets:', e.data);
+        }
+    });
+}
+
+function stopWatchingSecrets() {
+    clearTimeout(secretsRefresh);
+    if (secretsSource) {
+        secretsSource.close();
+        secretsSource = null;
+    }
+}
+
 async function fetchSecrets() {
     try {
-        const response = await fetch('/api/secrets');
+        const response = await fetch(withController('/api/secrets'));
         if (!response.ok) {
             const errorData = await response.json().catch(() => ({ message: response.statusText }));
             throw new Error(`HTTP error! Status: ${response.status} - ${errorData.message || 'Unknown error'}`);
@@ -17,9 +79,19 @@ async function fetchSecrets() {
                 secretItem.innerHTML = `
                     <div class="secret-item-header">
                         <div class="secret-name">${secret.name}</div>
+                        <div class="secret-status secret-status-${secret.status}"></div>
                     </div>
                     <div class="secret-preview"><span style="font-weight: bold">Namespace:</span> ${secret.namespace}</div>
                 `;
+                const status = secretItem.querySelector('.secret-status');
+                status.textContent = secret.secretExists === false ? `${secret.status} (secret missing)` : secret.status;
+                if (secret.message) {
+                    const message = document.createElement('div');
+                    message.className = 'secret-preview secret-message';
+                    message.textContent = secret.message;
+                    message.title = secret.message;
+                    secretItem.appendChild(message);
+                }
                 secretItem.addEventListener('click', function () {
                     const secretField = document.querySelectorAll('.input-field')[0];
 
@@ -35,6 +107,7 @@ async function fetchSecrets() {
 
                     // Close the modal
                     document.getElementById('secrets-modal').classList.remove('active');
+                    stopWatchingSecrets();
                 });
                 secretsList.appendChild(secretItem);
             });
@@ -51,7 +124,7 @@ async function fetchSecrets() {
 
 async function fetchSecretData(namespace, name) {
     try {
-        const response = await fetch(`/api/secret/${namespace}/${name}`);
+        const response = await fetch(withController(`/api/secret/${namespace}/${name}`));
         if (!response.ok) {
             throw new Error(`HTTP error! Status: ${response.status}`);
         }
@@ -76,7 +149,9 @@ async function seal() {
     try {
         console.log(secret);
         console.log(parsed);
-        const response = await fetch('/api/kubeseal', {
+        const scope = document.getElementById('scope-select').value;
+        const url = scope ? `/api/kubeseal?scope=${encodeURIComponent(scope)}` : '/api/kubeseal';
+        const response = await fetch(withController(url), {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json'